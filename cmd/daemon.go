@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"webscraper/internal/metrics"
+	"webscraper/internal/report"
+	"webscraper/internal/runstate"
+	"webscraper/internal/schedule"
+	"webscraper/internal/scraper"
+	"webscraper/internal/utils"
+)
+
+var daemonCommand = &command{
+	name:    "daemon",
+	summary: "Scrape the ticker file on a schedule, optionally serving the API",
+	help: `
+Stay running and scrape the ticker file at each time of daemon.schedule that
+falls on an ISX trading day, in daemon.timezone. Days in daemon.weekend
+(Friday and Saturday) and daemon.holidays are skipped. One browser is kept open across runs; each
+run gets a fresh run state and run report.
+
+With -serve the HTTP API is served as well; API jobs and scheduled runs never
+run at the same time. The daemon stops on Ctrl+C or SIGTERM.`,
+	run: runDaemonCommand,
+}
+
+func runDaemonCommand(fs *flag.FlagSet, args []string) error {
+	common := addCommonFlags(fs)
+	scrape := addScrapeFlags(fs)
+	tickerFile := fs.String("file", "", "CSV file of tickers to scrape (default scraper.tickersFile)")
+	serve := fs.Bool("serve", false, "Also serve the HTTP API")
+	apiAddr := fs.String("addr", "", "Listen address of the HTTP API with -serve (e.g. 127.0.0.1:8080), overrides config")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	a, err := common.open(false)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	if *tickerFile == "" {
+		*tickerFile = a.config.Scraper.TickersFile
+	}
+	if *apiAddr != "" {
+		a.config.API.Addr = *apiAddr
+	}
+	sched, err := newSchedule(a.config)
+	if err != nil {
+		return a.fail("%v", err)
+	}
+
+	ss, err := openSession(a, scrape)
+	if err != nil {
+		return a.fail("%v", err)
+	}
+	defer ss.Close()
+
+	// Stop between runs, or cut the current run short, on Ctrl+C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *serve {
+		apiServer, err := startAPI(ss, a.logger, a.config, *tickerFile)
+		if err != nil {
+			return a.fail("failed to start API server: %v", err)
+		}
+		defer apiServer.Close()
+	}
+
+	if err := runDaemon(ctx, ss.scraper, a.logger, a.config, sched, *tickerFile, ss.metrics); err != nil {
+		return a.fail("daemon failed: %v", err)
+	}
+	return nil
+}
+
+// newSchedule creates the daemon's schedule from the configuration.
+func newSchedule(config *utils.Config) (*schedule.Schedule, error) {
+	calendar, err := schedule.NewCalendar(config.Daemon.Weekend, config.Daemon.Holidays)
+	if err != nil {
+		return nil, err
+	}
+	return schedule.New(config.Daemon.Schedule, config.Daemon.Timezone, calendar)
+}
+
+// runDaemon keeps the scraper's browser open and runs the batch in
+// tickerFile at each time of sched, until ctx is cancelled. Each run gets a
+// fresh run state and report, and is bounded by the configured run timeout. A failed run is
+// logged and the daemon waits for the next one; it only returns early when
+// the browser itself has gone away.
+//
+// Parameters:
+//   - ctx: Context cancelled to stop the daemon, e.g. on SIGTERM
+//   - s: The scraper instance owning the browser
+//   - logger: Logger for tracking the process
+//   - config: Configuration providing the run settings
+//   - sched: Times to run at
+//   - tickerFile: Path to the CSV file of tickers, re-read before every run
+//   - m: Metrics recording each run, or nil
+//
+// Returns:
+//   - error: A schedule without trading days, or a browser that stopped running
+func runDaemon(ctx context.Context, s *scraper.Scraper, logger *utils.Logger, config *utils.Config, sched *schedule.Schedule, tickerFile string, m *metrics.Metrics) error {
+	daemon := config.Daemon
+	logger.Info("Daemon started: scraping %s at %q %s, skipping %s and %d holidays",
+		tickerFile, sched.Cron, sched.Location, strings.Join(daemon.Weekend, "/"), len(daemon.Holidays))
+
+	if daemon.RunOnStart {
+		if err := runScheduledBatch(ctx, s, logger, config, tickerFile, m); err != nil {
+			if !s.BrowserAlive() {
+				return fmt.Errorf("browser is no longer running: %v", err)
+			}
+			logger.Error("Run failed: %v", err)
+		}
+	}
+
+	for {
+		next := sched.Next(time.Now())
+		if next.IsZero() {
+			return fmt.Errorf("schedule %q never falls on a trading day", sched.Cron)
+		}
+		m.SetNextRun(next)
+		logger.Info("Next run at %s", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			logger.Info("Daemon stopping")
+			return nil
+		case <-timer.C:
+		}
+
+		if err := runScheduledBatch(ctx, s, logger, config, tickerFile, m); err != nil {
+			if ctx.Err() != nil {
+				logger.Info("Daemon stopping")
+				return nil
+			}
+			if !s.BrowserAlive() {
+				return fmt.Errorf("browser is no longer running: %v", err)
+			}
+			logger.Error("Scheduled run failed: %v", err)
+		}
+	}
+}
+
+// runScheduledBatch runs one scheduled batch over the tickers in tickerFile on
+// the daemon's browser.
+func runScheduledBatch(ctx context.Context, s *scraper.Scraper, logger *utils.Logger, config *utils.Config, tickerFile string, m *metrics.Metrics) error {
+	listed, err := utils.ReadTickers(tickerFile)
+	if err != nil {
+		return fmt.Errorf("error reading CSV file %s: %v", tickerFile, err)
+	}
+	tickers := utils.EnabledTickers(listed)
+	logger.Info("Starting scheduled run of %d tickers", len(tickers))
+	_, err = runBatch(ctx, s, logger, config, tickers, m)
+	return err
+}
+
+// batchMu serializes the batches started by the daemon and the API server,
+// which share one browser and the daemon.stateFile run state file.
+var batchMu sync.Mutex
+
+// runBatch runs a fresh batch over tickers, bounded by the configured run
+// timeout, once no other batch is running.
+func runBatch(ctx context.Context, s *scraper.Scraper, logger *utils.Logger, config *utils.Config, tickers []utils.Ticker, m *metrics.Metrics) (*report.Report, error) {
+	batchMu.Lock()
+	defer batchMu.Unlock()
+
+	runCtx := ctx
+	if d := config.Scraper.RunTimeout.D(); d > 0 {
+		var cancelRun context.CancelFunc
+		runCtx, cancelRun = context.WithTimeoutCause(ctx, d, scraper.ErrRunTimeout)
+		defer cancelRun()
+	}
+
+	// Time each batch on its own instead of every run since startup
+	s.ResetPerformanceTracker()
+
+	// Checkpoint apart from scraper.stateFile, which scrape -resume reads, so
+	// a scheduled batch never overwrites an interrupted manual run
+	state := runstate.New(config.Daemon.StateFile, utils.Symbols(tickers))
+	if err := state.Save(); err != nil {
+		logger.Error("Failed to save run state: %v", err)
+	}
+	return processTickerList(runCtx, s, logger, config, tickers, state, m)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"webscraper/internal/scraper"
+	"webscraper/internal/utils"
+	"webscraper/models"
+)
+
+var discoverCommand = &command{
+	name:    "discover",
+	summary: "Discover the listed companies and compare them with the ticker file",
+	help: `
+Read the companies listed on the exchange (symbol, name and sector) from the
+portal's listed-companies page and ticker strip, and compare them with the
+ticker file: companies not in the file are shown as NEW, tickers in the file
+the portal no longer lists as GONE. With -write the new companies are
+appended to the ticker file. "scrape -all" uses the same discovery.`,
+	run: runDiscover,
+}
+
+func runDiscover(fs *flag.FlagSet, args []string) error {
+	common := addCommonFlags(fs)
+	scrape := addScrapeFlags(fs)
+	tickerFile := fs.String("file", "", "CSV file of known tickers (default scraper.tickersFile)")
+	write := fs.Bool("write", false, "Append newly listed companies to the ticker file")
+	showAll := fs.Bool("list", false, "Print every listed company, not only the differences")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	a, err := common.open(true)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	if *tickerFile == "" {
+		*tickerFile = a.config.Scraper.TickersFile
+	}
+	listed, err := utils.ReadTickers(*tickerFile)
+	if err != nil {
+		return fmt.Errorf("error reading CSV file %s: %v", *tickerFile, err)
+	}
+	known := utils.Symbols(listed)
+
+	ss, err := openSession(a, scrape)
+	if err != nil {
+		return err
+	}
+	defer ss.Close()
+
+	companies, err := ss.scraper.DiscoverCompanies(context.Background())
+	if err != nil {
+		return err
+	}
+	added, missing := models.DiffCompanies(known, companies)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if *showAll {
+		isNew := make(map[string]bool, len(added))
+		for _, c := range added {
+			isNew[c.Symbol] = true
+		}
+		for _, c := range companies {
+			status := "LISTED"
+			if isNew[c.Symbol] {
+				status = "NEW"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", status, c.Symbol, c.Sector, c.Name)
+		}
+	} else {
+		for _, c := range added {
+			fmt.Fprintf(tw, "NEW\t%s\t%s\t%s\n", c.Symbol, c.Sector, c.Name)
+		}
+	}
+	for _, symbol := range missing {
+		fmt.Fprintf(tw, "GONE\t%s\t\t\n", symbol)
+	}
+	tw.Flush()
+	fmt.Printf("%d listed companies, %d tickers in %s: %d new, %d no longer listed\n",
+		len(companies), len(known), *tickerFile, len(added), len(missing))
+
+	if *write && len(added) > 0 {
+		if err := appendCompanies(*tickerFile, added); err != nil {
+			return fmt.Errorf("failed to update %s: %v", *tickerFile, err)
+		}
+		fmt.Printf("Added %d companies to %s\n", len(added), *tickerFile)
+	}
+	return nil
+}
+
+// discoverTickers returns the enabled tickers among the companies listed on
+// the portal for "scrape -all", logging how they differ from tickerFile.
+// Companies in tickerFile keep its settings, sector and name; the portal's
+// sector and name fill in what the file leaves empty. When discovery fails
+// the enabled tickers in tickerFile are used instead.
+func discoverTickers(ctx context.Context, s *scraper.Scraper, logger *utils.Logger, tickerFile string) ([]utils.Ticker, error) {
+	listed, fileErr := utils.ReadTickers(tickerFile)
+	companies, err := s.DiscoverCompanies(ctx)
+	if err != nil {
+		if fileErr != nil {
+			return nil, fmt.Errorf("failed to discover listed companies (%v) and to read %s: %v", err, tickerFile, fileErr)
+		}
+		enabled := utils.EnabledTickers(listed)
+		logger.Warn("Failed to discover listed companies, using the %d tickers in %s: %v", len(enabled), tickerFile, err)
+		return enabled, nil
+	}
+
+	known := make(map[string]utils.Ticker, len(listed))
+	if fileErr == nil {
+		for _, t := range listed {
+			known[t.Symbol] = t
+		}
+		added, missing := models.DiffCompanies(utils.Symbols(listed), companies)
+		for _, c := range added {
+			logger.Info("Newly listed company not in %s: %s (%s, %s)", tickerFile, c.Symbol, c.Name, c.Sector)
+		}
+		for _, symbol := range missing {
+			logger.Warn("Ticker %s in %s is no longer listed on the portal", symbol, tickerFile)
+		}
+	}
+
+	var tickers []utils.Ticker
+	for _, c := range companies {
+		t, ok := known[c.Symbol]
+		if !ok {
+			t = utils.Ticker{Company: c, Enabled: true}
+		}
+		if t.Sector == "" {
+			t.Sector = c.Sector
+		}
+		if t.Name == "" {
+			t.Name = c.Name
+		}
+		if t.Enabled {
+			tickers = append(tickers, t)
+		}
+	}
+	return tickers, nil
+}
+
+// appendCompanies adds companies to the ticker file, keeping its existing
+// rows and line endings. Each field goes in the column its header names, as
+// ReadTickers finds them; other columns are left empty.
+func appendCompanies(path string, companies []models.Company) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	columns, width, err := utils.TickerColumns(path)
+	if err != nil {
+		return err
+	}
+
+	crlf := bytes.Contains(content, []byte("\r\n"))
+	return utils.WriteFileAtomic(path, 1, func(w io.Writer) error {
+		if _, err := w.Write(content); err != nil {
+			return err
+		}
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			newline := "\n"
+			if crlf {
+				newline = "\r\n"
+			}
+			if _, err := io.WriteString(w, newline); err != nil {
+				return err
+			}
+		}
+		writer := csv.NewWriter(w)
+		writer.UseCRLF = crlf
+		for _, c := range companies {
+			row := make([]string, width)
+			fields := map[string]string{"symbol": c.Symbol, "sector": c.Sector, "name": c.Name}
+			for column, value := range fields {
+				if i, ok := columns[column]; ok {
+					row[i] = value
+				}
+			}
+			writer.Write(row)
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+	"webscraper/internal/export"
+	"webscraper/internal/utils"
+)
+
+var doctorCommand = &command{
+	name:    "doctor",
+	summary: "Check the configuration, store, portal and browser",
+	help: `
+Check everything a scrape depends on and print one line per check: the
+configuration and date range, the store, the export formats, the ticker
+file, the daemon schedule, whether the ISX portal answers, and the browser
+preflight checks. Exits with status 1 when any check fails.`,
+	run: runDoctor,
+}
+
+// portalTimeout bounds the request made to check that the portal answers.
+const portalTimeout = 15 * time.Second
+
+func runDoctor(fs *flag.FlagSet, args []string) error {
+	common := addCommonFlags(fs)
+	baseURL := fs.String("base-url", "", "ISX portal root URL, overrides config")
+	skipBrowser := fs.Bool("skip-browser", false, "Do not start the browser for the preflight checks")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	a, err := common.open(true)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	config := a.config
+	if *baseURL != "" {
+		config.Scraper.BaseURL = *baseURL
+	}
+
+	failed := 0
+	report := func(name string, detail string, err error) {
+		if err != nil {
+			failed++
+			fmt.Printf("FAIL  %s: %v\n", name, err)
+			return
+		}
+		fmt.Printf("OK    %s: %s\n", name, detail)
+	}
+
+	from, to, err := utils.ResolveDateRange(config.Scraper.FromDate, config.Scraper.ToDate)
+	report("Config", fmt.Sprintf("dates %s to %s", from.Format(utils.DateLayout), to.Format(utils.DateLayout)), err)
+
+	store, err := openStore(a, false)
+	if err != nil {
+		report("Store", "", err)
+	} else {
+		tickers, err := store.Tickers()
+		report("Store", fmt.Sprintf("%s, %d tickers stored", config.Storage.Store, len(tickers)), err)
+		store.Close()
+	}
+
+	exporters, err := export.ParseFormats(config.Export.Formats)
+	detail := "none"
+	if len(exporters) > 0 {
+		detail = fmt.Sprintf("%v to %s", config.Export.Formats, config.Export.Dir)
+	}
+	report("Export", detail, err)
+
+	tickers, err := utils.ReadTickers(config.Scraper.TickersFile)
+	report("Ticker file", fmt.Sprintf("%s, %d tickers, %d enabled", config.Scraper.TickersFile, len(tickers), len(utils.EnabledTickers(tickers))), err)
+
+	sched, err := newSchedule(config)
+	if err != nil {
+		report("Schedule", "", err)
+	} else {
+		next := sched.Next(time.Now())
+		if next.IsZero() {
+			report("Schedule", "", fmt.Errorf("%q has no trading day within five years", config.Daemon.Schedule))
+		} else {
+			report("Schedule", fmt.Sprintf("%q, next run %s", config.Daemon.Schedule, next.Format(time.RFC1123)), nil)
+		}
+	}
+
+	status, err := checkPortal(config.Scraper.BaseURL)
+	report("Portal", fmt.Sprintf("%s answered %s", config.Scraper.BaseURL, status), err)
+
+	if *skipBrowser {
+		fmt.Println("SKIP  Browser: -skip-browser given")
+	} else {
+		s, cancel, err := initializeScraper(a.logger, config)
+		if err != nil {
+			report("Browser", "", err)
+		} else {
+			for _, result := range s.RunPreflightChecks() {
+				report("Browser "+result.Name, "passed", result.Err)
+			}
+			s.Close()
+		}
+		cancel()
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d checks failed", failed)
+	}
+	return nil
+}
+
+// checkPortal requests the portal's root URL and returns the response status.
+func checkPortal(baseURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), portalTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return "", fmt.Errorf("%s answered %s", baseURL, resp.Status)
+	}
+	return resp.Status, nil
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"webscraper/internal/export"
+	"webscraper/internal/storage"
+	"webscraper/internal/utils"
+	"webscraper/models"
+)
+
+var listCommand = &command{
+	name:    "list",
+	summary: "List known tickers and their stored history",
+	help: `
+List the tickers in the ticker file and any other tickers with stored
+history, with their sector and name, whether the ticker file lists them,
+and the number of stored trading days and their date range. -sector limits
+the list to the given sectors.`,
+	run: runList,
+}
+
+var showCommand = &command{
+	name:    "show",
+	args:    "TICKER",
+	summary: "Print the stored history of a ticker",
+	help: `
+Print the stored history of a ticker, newest first, as a table or in any
+export format. Parquet output needs -out.`,
+	run: runShow,
+}
+
+var validateCommand = &command{
+	name:    "validate",
+	args:    "[TICKER...]",
+	summary: "Check stored history for corrupt or inconsistent rows",
+	help: `
+Check the stored history of the given tickers, or of every stored ticker,
+for rows that cannot be read, missing, repeated, misordered or future dates,
+prices outside the day's high-low range and negative counts. Exits with
+status 1 when any problem is found.`,
+	run: runValidate,
+}
+
+var exportCommand = &command{
+	name:    "export",
+	args:    "[TICKER...]",
+	summary: "Convert stored history to other formats or another store",
+	help: `
+Write the stored history of the given tickers, or of every stored ticker, in
+the export formats to <dir>/<TICKER>_data.<ext>, and/or copy it into another
+store, e.g. from the CSV files into a SQLite database:
+
+  webscraper export -format parquet
+  webscraper export -to-store sqlite:data/prices.db`,
+	run: runExport,
+}
+
+func runList(fs *flag.FlagSet, args []string) error {
+	common := addCommonFlags(fs)
+	tickerFile := fs.String("file", "", "CSV file of known tickers (default scraper.tickersFile)")
+	sectors := fs.String("sector", "", "List only the tickers in these comma-separated sectors, e.g. Banks")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	a, err := common.open(true)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	store, err := openStore(a, false)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	file := *tickerFile
+	if file == "" {
+		file = a.config.Scraper.TickersFile
+	}
+	listed, err := utils.ReadTickers(file)
+	if err != nil {
+		if *tickerFile != "" {
+			return fmt.Errorf("error reading CSV file %s: %v", file, err)
+		}
+		a.logger.Warn("Failed to read ticker file %s: %v", file, err)
+	}
+	stored, err := store.Tickers()
+	if err != nil {
+		return fmt.Errorf("failed to list stored tickers: %v", err)
+	}
+
+	// Listed tickers first, in file order, then those only in the store
+	inFile := make(map[string]bool, len(listed))
+	tickers := make([]utils.Ticker, 0, len(listed)+len(stored))
+	for _, t := range listed {
+		if !inFile[t.Symbol] {
+			inFile[t.Symbol] = true
+			tickers = append(tickers, t)
+		}
+	}
+	for _, symbol := range stored {
+		if !inFile[symbol] {
+			tickers = append(tickers, utils.Ticker{Company: models.Company{Symbol: symbol}})
+		}
+	}
+	tickers = utils.FilterSectors(tickers, *sectors)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TICKER\tSECTOR\tLISTED\tDAYS\tFIRST\tLAST\tNAME")
+	for _, t := range tickers {
+		ticker := t.Symbol
+		listedText := "yes"
+		if !inFile[ticker] {
+			listedText = "no"
+		} else if !t.Enabled {
+			listedText = "disabled"
+		}
+		sector, name := t.Sector, t.Name
+		if sector == "" {
+			sector = "-"
+		}
+		data, err := store.Load(ticker)
+		if err != nil {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t-\t-\t-\t%s (%v)\n", ticker, sector, listedText, name, err)
+			continue
+		}
+		first, last := "-", "-"
+		if len(data) > 0 {
+			// Scan rather than trust the order, which validate checks
+			firstDate, lastDate := data[0].Date, data[0].Date
+			for _, record := range data[1:] {
+				if record.Date.Before(firstDate) {
+					firstDate = record.Date
+				}
+				if record.Date.After(lastDate) {
+					lastDate = record.Date
+				}
+			}
+			first, last = firstDate.Format(models.DateLayout), lastDate.Format(models.DateLayout)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", ticker, sector, listedText, len(data), first, last, name)
+	}
+	return tw.Flush()
+}
+
+func runShow(fs *flag.FlagSet, args []string) error {
+	common := addCommonFlags(fs)
+	fromDate := fs.String("from", "", "First trading date to show (YYYY-MM-DD)")
+	toDate := fs.String("to", "", "Last trading date to show (YYYY-MM-DD)")
+	format := fs.String("format", "table", "Output format: table or an export format ("+strings.Join(export.Formats(), ", ")+")")
+	limit := fs.Int("limit", 0, "Show only the newest N trading days (0 = all)")
+	out := fs.String("out", "", "Write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one ticker")
+	}
+	ticker := strings.ToUpper(fs.Arg(0))
+
+	from, to, err := parseDateFlags(*fromDate, *toDate)
+	if err != nil {
+		return err
+	}
+	var exporter export.Exporter
+	if *format != "table" {
+		if exporter, err = export.New(*format); err != nil {
+			return err
+		}
+		if exporter.Name() == "parquet" && *out == "" {
+			return fmt.Errorf("parquet output needs -out")
+		}
+	}
+
+	a, err := common.open(true)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	if exporter != nil {
+		exporter = export.WithCSVSchema(exporter, a.config.Storage.CSVSchema)
+	}
+	store, err := openStore(a, false)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	data, err := store.Load(ticker)
+	if err != nil {
+		return fmt.Errorf("failed to load history of %s: %v", ticker, err)
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("no stored history for %s in %s", ticker, a.config.Storage.Store)
+	}
+	data = models.FilterDates(data, from, to)
+	if *limit > 0 && len(data) > *limit {
+		data = data[:*limit]
+	}
+
+	tickers, err := lookupTickers(a.logger, a.config.Scraper.TickersFile, []string{ticker})
+	if err != nil {
+		return err
+	}
+	company := tickers[0].Company
+	if *out != "" {
+		return utils.WriteFileAtomic(*out, 0, func(w io.Writer) error {
+			return writeHistory(w, exporter, company, data)
+		})
+	}
+	return writeHistory(os.Stdout, exporter, company, data)
+}
+
+// writeHistory writes data with exporter, or as a table if exporter is nil.
+func writeHistory(w io.Writer, exporter export.Exporter, company models.Company, data []models.StockData) error {
+	if exporter != nil {
+		return exporter.Export(w, company, data)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "DATE\tOPEN\tHIGH\tLOW\tCLOSE\tCHANGE\tCHANGE%\tVOLUME\tSHARES\tTRADES\t")
+	for _, record := range data {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%.2f\t%d\t%d\t%d\t\n",
+			record.Date.Format(models.DateLayout), record.OpenPrice, record.HighPrice, record.LowPrice,
+			record.ClosePrice, record.Change, record.ChangePerc, record.Volume, record.TotalShares, record.NumTrades)
+	}
+	return tw.Flush()
+}
+
+func runValidate(fs *flag.FlagSet, args []string) error {
+	common := addCommonFlags(fs)
+	maxIssues := fs.Int("max-issues", 20, "Problems shown per ticker (0 = all)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	a, err := common.open(true)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	store, err := openStore(a, false)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	tickers, err := storedTickers(store, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, ticker := range tickers {
+		data, err := store.Load(ticker)
+		if err != nil {
+			fmt.Printf("%s: cannot be read: %v\n", ticker, err)
+			failed++
+			continue
+		}
+		if len(data) == 0 {
+			fmt.Printf("%s: no stored history\n", ticker)
+			failed++
+			continue
+		}
+
+		issues := models.Validate(data)
+		if len(issues) == 0 {
+			fmt.Printf("%s: OK, %d trading days\n", ticker, len(data))
+			continue
+		}
+		failed++
+		fmt.Printf("%s: %d problems in %d trading days\n", ticker, len(issues), len(data))
+		for i, issue := range issues {
+			if *maxIssues > 0 && i == *maxIssues {
+				fmt.Printf("  ... and %d more\n", len(issues)-i)
+				break
+			}
+			fmt.Printf("  %s\n", issue)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tickers have problems", failed, len(tickers))
+	}
+	return nil
+}
+
+func runExport(fs *flag.FlagSet, args []string) error {
+	common := addCommonFlags(fs)
+	formats := fs.String("format", "", "Comma-separated export formats ("+strings.Join(export.Formats(), ", ")+"), default export.formats")
+	dir := fs.String("dir", "", "Directory to write the exported files to, overrides export.dir")
+	toStore := fs.String("to-store", "", "Also copy the history into this store, csv:<dir> or sqlite:<file>")
+	force := fs.Bool("force", false, "With -to-store, save even when the target holds more trading days")
+	sectors := fs.String("sector", "", "Export only the tickers in these comma-separated sectors, e.g. Banks")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	a, err := common.open(true)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	config := a.config
+
+	if *formats != "" {
+		config.Export.Formats = strings.Split(*formats, ",")
+	}
+	if *dir != "" {
+		config.Export.Dir = *dir
+	}
+	exporters, err := export.ParseFormats(config.Export.Formats)
+	if err != nil {
+		return err
+	}
+	for i, e := range exporters {
+		exporters[i] = export.WithCSVSchema(e, config.Storage.CSVSchema)
+	}
+	if len(exporters) == 0 && *toStore == "" {
+		fs.Usage()
+		return fmt.Errorf("nothing to do; give -format or -to-store")
+	}
+
+	store, err := openStore(a, false)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var target storage.Store
+	if *toStore != "" {
+		options := storage.Options{Backups: config.Storage.Backups, Force: *force, CSVSchema: config.Storage.CSVSchema}
+		target, err = storage.Open(*toStore, options, a.logger)
+		if err != nil {
+			return fmt.Errorf("failed to open store %s: %v", *toStore, err)
+		}
+		defer target.Close()
+	}
+
+	symbols, err := storedTickers(store, fs.Args())
+	if err != nil {
+		return err
+	}
+	tickers, err := lookupTickers(a.logger, config.Scraper.TickersFile, symbols)
+	if err != nil {
+		return err
+	}
+	tickers = utils.FilterSectors(tickers, *sectors)
+	if len(tickers) == 0 {
+		return fmt.Errorf("no stored tickers in sector %s", *sectors)
+	}
+
+	failed := 0
+	for _, t := range tickers {
+		if err := exportTicker(store, target, config.Export.Dir, exporters, t.Company); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", t.Symbol, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tickers could not be exported", failed, len(tickers))
+	}
+	return nil
+}
+
+// exportTicker writes company's stored history in every format and copies it
+// into target, if given.
+func exportTicker(store, target storage.Store, dir string, exporters []export.Exporter, company models.Company) error {
+	ticker := company.Symbol
+	data, err := store.Load(ticker)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("no stored history")
+	}
+
+	for _, exporter := range exporters {
+		// A CSV export into the CSV store's directory is the stored file itself
+		if filepath.Clean(export.Path(exporter, dir, ticker)) == filepath.Clean(store.Location(ticker)) {
+			continue
+		}
+		path, err := export.WriteFile(exporter, dir, company, data)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: wrote %d trading days to %s\n", ticker, len(data), path)
+	}
+	if target != nil {
+		if err := target.Save(ticker, data); err != nil {
+			return err
+		}
+		fmt.Printf("%s: copied %d trading days to %s\n", ticker, len(data), target.Location(ticker))
+	}
+	return nil
+}
+
+// openStore opens the configured store for a command working on stored
+// history.
+func openStore(a *app, force bool) (storage.Store, error) {
+	options := storage.Options{Backups: a.config.Storage.Backups, Force: force, CSVSchema: a.config.Storage.CSVSchema}
+	store, err := storage.Open(a.config.Storage.Store, options, a.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %v", err)
+	}
+	return store, nil
+}
+
+// storedTickers returns the given tickers, upper-cased, or every ticker in
+// the store if none are given.
+func storedTickers(store storage.Store, args []string) ([]string, error) {
+	if len(args) > 0 {
+		tickers := make([]string, len(args))
+		for i, ticker := range args {
+			tickers[i] = strings.ToUpper(ticker)
+		}
+		return tickers, nil
+	}
+	tickers, err := store.Tickers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stored tickers: %v", err)
+	}
+	if len(tickers) == 0 {
+		return nil, fmt.Errorf("no stored history found")
+	}
+	return tickers, nil
+}
+
+// parseDateFlags parses optional YYYY-MM-DD -from and -to values.
+func parseDateFlags(from, to string) (time.Time, time.Time, error) {
+	var fromDate, toDate time.Time
+	var err error
+	if from != "" {
+		if fromDate, err = time.Parse(utils.DateLayout, from); err != nil {
+			return fromDate, toDate, fmt.Errorf("invalid from date %q (want YYYY-MM-DD): %v", from, err)
+		}
+	}
+	if to != "" {
+		if toDate, err = time.Parse(utils.DateLayout, to); err != nil {
+			return fromDate, toDate, fmt.Errorf("invalid to date %q (want YYYY-MM-DD): %v", to, err)
+		}
+	}
+	if !fromDate.IsZero() && !toDate.IsZero() && fromDate.After(toDate) {
+		return fromDate, toDate, fmt.Errorf("from date %s is after to date %s", from, to)
+	}
+	return fromDate, toDate, nil
+}
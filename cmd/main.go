@@ -1,213 +1,227 @@
-// Package main provides the entry point for the web scraper application.
-// It supports scraping stock data from the Iraq Stock Exchange (ISX) for either
-// a single ticker or multiple tickers from a CSV file.
-package main
-
-import (
-	"context"
-	"flag"
-	"fmt"
-	"log"
-	"os"
-	"time"
-	"webscraper/internal/scraper"
-	"webscraper/internal/utils"
-
-	"github.com/chromedp/chromedp"
-)
-
-// processSingleTicker handles the scraping process for a single stock ticker.
-// It fetches the stock data and saves it to a CSV file.
-//
-// Parameters:
-//   - s: The scraper instance
-//   - logger: Logger for tracking the process
-//   - ticker: The stock ticker symbol to process
-//
-// Returns:
-//   - error: Any error that occurred during processing
-func processSingleTicker(s *scraper.Scraper, logger *utils.Logger, ticker string) error {
-	logger.Info("Processing ticker: %s", ticker)
-
-	// Fetch stock data from the website
-	stockDataList, err := s.GetStockData(ticker)
-	if err != nil {
-		logger.Error("Error processing %s: %v", ticker, err)
-		return err
-	}
-
-	// Save the fetched data to a CSV file
-	err = s.SaveToCSV(ticker, stockDataList)
-	if err != nil {
-		logger.Error("Error saving data for %s: %v", ticker, err)
-		return err
-	}
-
-	logger.Info("Successfully processed %s. Data saved to output/%s_data.csv", ticker, ticker)
-	return nil
-}
-
-// processTickerList handles the scraping process for multiple stock tickers.
-// It processes each ticker sequentially with a delay between requests.
-//
-// Parameters:
-//   - s: The scraper instance
-//   - logger: Logger for tracking the process
-//   - tickers: Slice of ticker symbols to process
-//
-// Returns:
-//   - error: Any error that occurred during processing
-func processTickerList(s *scraper.Scraper, logger *utils.Logger, tickers []string) error {
-	totalTickers := len(tickers)
-	logger.Info("Starting to process %d tickers", totalTickers)
-
-	for i, ticker := range tickers {
-		logger.Info("Processing ticker %d/%d: %s", i+1, totalTickers, ticker)
-
-		err := processSingleTicker(s, logger, ticker)
-		if err != nil {
-			logger.Error("Failed to process ticker %s: %v", ticker, err)
-			time.Sleep(10 * time.Second)
-			continue
-		}
-
-		if i < totalTickers-1 {
-			logger.Debug("Waiting 10 seconds before next ticker")
-			time.Sleep(10 * time.Second)
-		}
-	}
-
-	// Generate and log aggregate performance report
-	report := s.GetPerformanceTracker().GenerateAggregateReport()
-	logger.Info("Aggregate Performance Report:\n%s", report)
-
-	logger.Info("Completed processing %d tickers", totalTickers)
-	return nil
-}
-
-// initializeScraper sets up the Chrome browser and creates necessary directories.
-// It configures the browser with Arabic language support and creates the screenshots directory.
-//
-// Parameters:
-//   - logger: Logger for tracking the initialization process
-//   - config: Configuration for the scraper
-//
-// Returns:
-//   - *scraper.Scraper: Configured scraper instance
-//   - context.CancelFunc: Function to cancel the browser context
-//   - error: Any error that occurred during initialization
-func initializeScraper(logger *utils.Logger, config *utils.Config) (*scraper.Scraper, context.CancelFunc, error) {
-	logger.Debug("Initializing Chrome with Arabic support")
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("lang", "ar"),
-		chromedp.Flag("disable-gpu", true),
-		chromedp.Flag("no-sandbox", true),
-		chromedp.Flag("disable-setuid-sandbox", true),
-		chromedp.NoSandbox,
-		chromedp.Flag("headless", config.Scraper.Browser.Headless),
-		chromedp.Flag("start-maximized", true),
-		chromedp.Flag("enable-logging", config.Scraper.Browser.Debug),
-		chromedp.Flag("v", "1"),
-	)
-
-	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
-	ctx, _ := chromedp.NewContext(allocCtx, chromedp.WithLogf(logger.Debug))
-
-	// Test browser launch
-	if err := chromedp.Run(ctx, chromedp.Navigate("about:blank")); err != nil {
-		logger.Error("Failed to launch browser: %v", err)
-		return nil, cancel, err
-	}
-
-	// Create screenshots directory
-	if err := os.MkdirAll("logs/screenshots", 0755); err != nil {
-		logger.Error("Failed to create screenshots directory: %v", err)
-		return nil, cancel, err
-	}
-
-	return scraper.NewScraper(logger, ctx, cancel, config), cancel, nil
-}
-
-func main() {
-	startTime := time.Now()
-
-	// Set custom temp directory before any other operations
-	tempDir := "C:/GoProjects/webscraper/temp_builds"
-	if err := os.MkdirAll(tempDir, 0755); err != nil {
-		log.Printf("Warning: Failed to create temp directory: %v", err)
-		// Try to use system temp directory as fallback
-		tempDir = os.TempDir()
-	}
-	os.Setenv("GOTMPDIR", tempDir)
-
-	// Define and parse command-line flags
-	singleTicker := flag.String("ticker", "", "Single ticker to process")
-	tickerFile := flag.String("file", "", "Path to CSV file containing tickers")
-	flag.Parse()
-
-	// Initialize logger for the application
-	logger, err := utils.NewLogger()
-	if err != nil {
-		log.Fatalf("Failed to initialize logger: %v", err)
-	}
-	defer logger.Close()
-
-	logger.Info("Starting web scraper application")
-
-	// Load configuration
-	configPath := os.Getenv("CONFIG_PATH")
-	if configPath == "" {
-		configPath = "configs/config.yaml"
-	}
-
-	config, err := utils.LoadConfig(configPath)
-	if err != nil {
-		logger.Fatal("Failed to load configuration: %v", err)
-	}
-
-	// Update initializeScraper to use config
-	s, cancel, err := initializeScraper(logger, config)
-	if err != nil {
-		logger.Fatal("Failed to initialize scraper: %v", err)
-	}
-
-	// Run preflight checks
-	if err := s.PreflightCheck(); err != nil {
-		logger.Fatal("Preflight check failed: %v", err)
-	}
-
-	// Ensure cleanup happens in the correct order
-	defer func() {
-		fmt.Println("Starting cleanup...")
-		s.Close() // Close the scraper first
-		cancel()  // Then cancel the context
-		fmt.Println("Cleanup completed")
-	}()
-
-	// Process based on input flags
-	if *singleTicker != "" {
-		err = processSingleTicker(s, logger, *singleTicker)
-		if err != nil {
-			logger.Fatal("Failed to process ticker %s: %v", *singleTicker, err)
-		}
-	} else if *tickerFile != "" {
-		tickers, err := utils.ReadTickersFromCSV(*tickerFile)
-		if err != nil {
-			logger.Fatal("Error reading CSV file %s: %v", *tickerFile, err)
-		}
-
-		logger.Info("Found %d tickers to process", len(tickers))
-		err = processTickerList(s, logger, tickers)
-		if err != nil {
-			logger.Fatal("Failed to process ticker list: %v", err)
-		}
-	} else {
-		logger.Fatal("No input specified. Use -ticker for single ticker or -file for ticker list")
-	}
-
-	// Log overall execution time
-	duration := time.Since(startTime)
-	logger.Info("Total execution time: %v", duration.Round(time.Second))
-
-	logger.Info("Scraping completed successfully!")
-}
+// Package main provides the entry point for the web scraper application.
+// It scrapes stock data from the Iraq Stock Exchange (ISX) and works with the
+// stored history through subcommands:
+//
+//	scrape    scrape tickers once, given as arguments, a CSV file or -all
+//	discover  discover the listed companies and compare them with the ticker file
+//	daemon    scrape the ticker file on a schedule, optionally serving the API
+//	serve     serve the HTTP API for scrape jobs and stored history
+//	list      list known tickers and their stored history
+//	show      print the stored history of a ticker
+//	validate  check stored history for corrupt or inconsistent rows
+//	export    convert stored history to other formats or another store
+//	doctor    check the configuration, store, portal and browser
+//
+// Run "webscraper help <command>" for the flags of each command.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"webscraper/internal/utils"
+)
+
+// command is a subcommand of the application.
+type command struct {
+	name    string
+	args    string // synopsis of the arguments after the flags
+	summary string // one line for the command list
+	help    string // description shown by "help <command>"
+	run     func(fs *flag.FlagSet, args []string) error
+}
+
+// commands lists the subcommands in the order they are shown in the help.
+var commands []*command
+
+func init() {
+	commands = []*command{
+		scrapeCommand,
+		discoverCommand,
+		daemonCommand,
+		serveCommand,
+		listCommand,
+		showCommand,
+		validateCommand,
+		exportCommand,
+		doctorCommand,
+	}
+}
+
+// findCommand returns the subcommand with the given name, or nil.
+func findCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// flagSet creates the flag set of c, whose usage message shows the help text
+// of the command followed by its flags.
+func (c *command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: webscraper %s [flags] %s\n\n", c.name, c.args)
+		fmt.Fprintf(out, "%s\n\nFlags:\n", strings.TrimSpace(c.help))
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// usage prints the list of subcommands.
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: webscraper <command> [flags] [arguments]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(w, "\nRun \"webscraper help <command>\" for the flags of a command.\n")
+}
+
+// loggedError is an error the command has already written to its log; main
+// only turns it into the exit status.
+type loggedError struct {
+	error
+}
+
+// run executes the command line args and returns the exit status.
+func run(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return 2
+	}
+
+	name, rest := args[0], args[1:]
+	switch {
+	case name == "help" || name == "-h" || name == "-help" || name == "--help":
+		if len(rest) == 0 {
+			usage(os.Stdout)
+			return 0
+		}
+		c := findCommand(rest[0])
+		if c == nil {
+			fmt.Fprintf(os.Stderr, "webscraper: unknown command %q\n", rest[0])
+			return 2
+		}
+		fs := c.flagSet()
+		fs.SetOutput(os.Stdout)
+		c.run(fs, []string{"-h"})
+		return 0
+	case strings.HasPrefix(name, "-"):
+		// Flags without a command are the pre-subcommand invocation, e.g.
+		// "-ticker BBOB" or "-file TICKERS.csv"
+		log.Printf("Warning: running without a command is deprecated, use \"webscraper scrape %s\"", strings.Join(args, " "))
+		name, rest = scrapeCommand.name, args
+	}
+
+	c := findCommand(name)
+	if c == nil {
+		fmt.Fprintf(os.Stderr, "webscraper: unknown command %q\n\n", name)
+		usage(os.Stderr)
+		return 2
+	}
+
+	err := c.run(c.flagSet(), rest)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		var logged loggedError
+		if !errors.As(err, &logged) {
+			fmt.Fprintf(os.Stderr, "webscraper %s: %v\n", c.name, err)
+		}
+		return 1
+	}
+	return 0
+}
+
+// commonFlags are accepted by every command.
+type commonFlags struct {
+	config    string
+	store     string
+	logLevel  string
+	logFormat string
+}
+
+func addCommonFlags(fs *flag.FlagSet) *commonFlags {
+	f := &commonFlags{}
+	fs.StringVar(&f.config, "config", "", "Configuration file (default $CONFIG_PATH or configs/config.yaml)")
+	fs.StringVar(&f.store, "store", "", "Where price history is kept, csv:<dir> or sqlite:<file>, overrides config")
+	fs.StringVar(&f.logLevel, "log-level", "", "Log level (debug, info, warn, error), overrides config")
+	fs.StringVar(&f.logFormat, "log-format", "", "Log format (text or json), overrides config")
+	return f
+}
+
+// app is the configuration and logger a command runs with.
+type app struct {
+	config *utils.Config
+	logger *utils.Logger
+}
+
+// open loads the configuration, applies the flags to it and starts the
+// logger. Quiet commands print their results on stdout, so they log to
+// stderr, and only warnings and errors unless -log-level is given.
+func (f *commonFlags) open(quiet bool) (*app, error) {
+	configPath := f.config
+	if configPath == "" {
+		configPath = os.Getenv("CONFIG_PATH")
+	}
+	if configPath == "" {
+		configPath = "configs/config.yaml"
+	}
+
+	config, err := utils.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %v", err)
+	}
+	if f.store != "" {
+		config.Storage.Store = f.store
+	}
+	if quiet {
+		config.Logging.Console = os.Stderr
+		config.Logging.Level = "warn"
+	}
+	if f.logLevel != "" {
+		config.Logging.Level = f.logLevel
+	}
+	if f.logFormat != "" {
+		config.Logging.Format = f.logFormat
+	}
+
+	logger, err := utils.NewLogger(config.Logging)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize logger: %v", err)
+	}
+	return &app{config: config, logger: logger}, nil
+}
+
+// fail logs an error that ends the command and returns it for the exit
+// status.
+func (a *app) fail(format string, args ...interface{}) error {
+	err := fmt.Errorf(format, args...)
+	a.logger.Error("%v", err)
+	return loggedError{err}
+}
+
+func (a *app) Close() {
+	a.logger.Close()
+}
+
+func main() {
+	// Set custom temp directory before any other operations
+	tempDir := "C:/GoProjects/webscraper/temp_builds"
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		log.Printf("Warning: Failed to create temp directory: %v", err)
+		// Try to use system temp directory as fallback
+		tempDir = os.TempDir()
+	}
+	os.Setenv("GOTMPDIR", tempDir)
+
+	os.Exit(run(os.Args[1:]))
+}
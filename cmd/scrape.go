@@ -0,0 +1,484 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"webscraper/internal/metrics"
+	"webscraper/internal/report"
+	"webscraper/internal/runstate"
+	"webscraper/internal/scraper"
+	"webscraper/internal/utils"
+	"webscraper/models"
+)
+
+var scrapeCommand = &command{
+	name:    "scrape",
+	args:    "[TICKER...]",
+	summary: "Scrape tickers once, given as arguments, a CSV file or -all",
+	help: `
+Scrape the price history of the given tickers, merge it into the store and
+export it in the configured formats.
+
+The ticker file (a -file, or scraper.tickersFile for tickers given as
+arguments) supplies each ticker's sector and name for the JSON, JSON Lines
+and Parquet exports and the run report; CSV files keep their fixed columns.
+Its columns are found by header: Ticker is required; Sector, Name, FromDate
+(overrides scraper.fromDate for that ticker) and Enabled (false skips the
+ticker in batches) are optional. -sector limits the run to the given
+sectors.
+
+With -all, the companies listed on the portal are discovered first, as by the
+discover command, and all of them are scraped; if discovery fails, the
+tickers in scraper.tickersFile are scraped instead.
+
+A single ticker is scraped in one browser tab. Several tickers, a -file or
+-all run as a batch: tickers are spread over the configured number of tabs,
+progress is recorded in the run state file for -resume and -only-failed, and
+a run report is written at the end. The command exits with status 1 when
+more tickers fail than report.maxFailures allows.`,
+	run: runScrape,
+}
+
+func runScrape(fs *flag.FlagSet, args []string) error {
+	common := addCommonFlags(fs)
+	scrape := addScrapeFlags(fs)
+	tickerFile := fs.String("file", "", "Scrape the tickers listed in this CSV file")
+	all := fs.Bool("all", false, "Scrape every company listed on the portal, or the tickers in scraper.tickersFile if they cannot be discovered")
+	sectors := fs.String("sector", "", "Scrape only the tickers in these comma-separated sectors, e.g. Banks")
+	singleTicker := fs.String("ticker", "", "Single ticker to process (deprecated, pass the ticker as an argument)")
+	resume := fs.Bool("resume", false, "For a batch, skip tickers the previous run already completed")
+	onlyFailed := fs.Bool("only-failed", false, "For a batch, process only the tickers that failed in the previous run")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	symbols := fs.Args()
+	if *singleTicker != "" {
+		symbols = append([]string{*singleTicker}, symbols...)
+	}
+	if len(symbols) == 0 && *tickerFile == "" && !*all {
+		fs.Usage()
+		return fmt.Errorf("no tickers given; pass tickers as arguments, -file or -all")
+	}
+	if *all && *tickerFile != "" {
+		return fmt.Errorf("-all and -file cannot be combined")
+	}
+
+	a, err := common.open(false)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	logger, config := a.logger, a.config
+	logger.Info("Starting web scraper application")
+	startTime := time.Now()
+
+	// Tickers given by symbol take their sector, name and settings from the
+	// ticker file when it lists them
+	tickers, err := lookupTickers(logger, config.Scraper.TickersFile, symbols)
+	if err != nil {
+		return a.fail("%v", err)
+	}
+	if *tickerFile != "" {
+		listed, err := utils.ReadTickers(*tickerFile)
+		if err != nil {
+			return a.fail("error reading CSV file %s: %v", *tickerFile, err)
+		}
+		tickers = append(tickers, utils.EnabledTickers(listed)...)
+	}
+	// A ticker named twice would be scraped by two tabs into the same file
+	tickers = utils.UniqueTickers(tickers)
+	if !*all {
+		if tickers = utils.FilterSectors(tickers, *sectors); len(tickers) == 0 {
+			return a.fail("no tickers to scrape in sector %s", *sectors)
+		}
+	}
+	batch := len(tickers) > 1 || *tickerFile != "" || *all
+	if !batch && (*resume || *onlyFailed) {
+		return a.fail("-resume and -only-failed apply only to batches; ticker %s is scraped on its own", tickers[0].Symbol)
+	}
+
+	ss, err := openSession(a, scrape)
+	if err != nil {
+		return a.fail("%v", err)
+	}
+	defer ss.Close()
+	s := ss.scraper
+
+	// Bound the whole run by the configured deadline, if any
+	runCtx := context.Background()
+	if d := config.Scraper.RunTimeout.D(); d > 0 {
+		var cancelRun context.CancelFunc
+		runCtx, cancelRun = context.WithTimeoutCause(runCtx, d, scraper.ErrRunTimeout)
+		defer cancelRun()
+	}
+
+	if *all {
+		listed, err := discoverTickers(runCtx, s, logger, config.Scraper.TickersFile)
+		if err != nil {
+			return a.fail("%v", err)
+		}
+		tickers = utils.UniqueTickers(append(tickers, listed...))
+		if tickers = utils.FilterSectors(tickers, *sectors); len(tickers) == 0 {
+			return a.fail("no listed companies in sector %s", *sectors)
+		}
+	}
+
+	if !batch {
+		// Scrape in a tab of its own so a lost tab can be replaced on retry
+		ticker := tickers[0]
+		tab, err := s.NewTab()
+		if err != nil {
+			return a.fail("failed to open browser tab: %v", err)
+		}
+		start := time.Now()
+		err = processSingleTicker(runCtx, tab, logger, ticker)
+		result := tickerResult{ticker: ticker, err: err, timedOut: scraper.IsTimeout(err), duration: time.Since(start), stats: tab.LastStats()}
+		ss.metrics.ObserveTicker(result.report())
+		tab.CloseTab()
+		if err != nil {
+			return a.fail("failed to process ticker %s: %v", ticker.Symbol, err)
+		}
+	} else {
+		logger.Info("Found %d tickers to process", len(tickers))
+		state, tickers, err := prepareRunState(logger, config.Scraper.StateFile, tickers, *resume, *onlyFailed)
+		if err != nil {
+			return a.fail("failed to prepare run state: %v", err)
+		}
+		if _, err := processTickerList(runCtx, s, logger, config, tickers, state, ss.metrics); err != nil {
+			// Report the failure through the exit status after cleanup
+			logger.Error("Failed to process ticker list: %v", err)
+			logger.Info("Total execution time: %v", time.Since(startTime).Round(time.Second))
+			logger.Info("Scraping completed with failures")
+			return loggedError{err}
+		}
+	}
+
+	// Log overall execution time
+	logger.Info("Total execution time: %v", time.Since(startTime).Round(time.Second))
+	logger.Info("Scraping completed successfully!")
+	return nil
+}
+
+// lookupTickers returns the tickers with the given symbols, taking their
+// sector, name and settings from tickerFile where it lists them. A ticker
+// file that cannot be read is logged and only the symbols are used. An
+// invalid symbol is an error.
+func lookupTickers(logger *utils.Logger, tickerFile string, symbols []string) ([]utils.Ticker, error) {
+	known := make(map[string]utils.Ticker)
+	if len(symbols) > 0 {
+		listed, err := utils.ReadTickers(tickerFile)
+		if err != nil {
+			logger.Debug("Failed to read ticker file %s: %v", tickerFile, err)
+		}
+		for _, t := range listed {
+			known[t.Symbol] = t
+		}
+	}
+
+	tickers := make([]utils.Ticker, 0, len(symbols))
+	for _, symbol := range symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if !models.ValidSymbol(symbol) {
+			return nil, fmt.Errorf("invalid ticker symbol %q", symbol)
+		}
+		t, ok := known[symbol]
+		if !ok {
+			t = utils.Ticker{Company: models.Company{Symbol: symbol}}
+		}
+		// Naming a ticker scrapes it even if the file disables it
+		t.Enabled = true
+		tickers = append(tickers, t)
+	}
+	return tickers, nil
+}
+
+// processSingleTicker handles the scraping process for a single stock ticker.
+// It fetches the stock data and saves it to a CSV file.
+//
+// Parameters:
+//   - ctx: Context carrying the run deadline
+//   - s: The scraper instance
+//   - logger: Logger for tracking the process
+//   - t: The ticker to process, with its settings from the ticker file
+//
+// Returns:
+//   - error: Any error that occurred during processing
+func processSingleTicker(ctx context.Context, s *scraper.Scraper, logger *utils.Logger, t utils.Ticker) error {
+	ticker := t.Symbol
+	logger = logger.With("ticker", ticker)
+	logger.Info("Processing ticker: %s", ticker)
+
+	// Fetch stock data from the website
+	stockDataList, err := s.GetTickerData(ctx, t)
+	if err != nil {
+		logger.Error("Error processing %s: %v", ticker, err)
+		return err
+	}
+
+	// Save the fetched data to the store and export formats
+	err = s.Save(ticker, stockDataList)
+	if err != nil {
+		logger.Error("Error saving data for %s: %v", ticker, err)
+		return err
+	}
+
+	logger.Info("Successfully processed %s", ticker)
+	return nil
+}
+
+// tickerResult records the outcome of processing one ticker in a batch.
+type tickerResult struct {
+	ticker   utils.Ticker
+	err      error
+	timedOut bool
+	duration time.Duration
+	stats    scraper.ScrapeStats
+}
+
+// processTickerList handles the scraping process for multiple stock tickers.
+// Tickers are distributed over a pool of browser tabs sharing one browser;
+// portal requests from all tabs go through the scraper's rate limiter. Results
+// are reported in input order regardless of which worker finished first, both
+// as a table on the console and as a JSON report at config.Report.Path.
+//
+// Parameters:
+//   - ctx: Context carrying the run deadline; tickers not started before it
+//     expires are reported as timed out
+//   - s: The scraper instance owning the browser
+//   - logger: Logger for tracking the process
+//   - config: Configuration providing the worker count and waits
+//   - tickers: Tickers to process, with their settings from the ticker file
+//   - state: Run state updated after each ticker
+//   - m: Metrics recording each ticker and the run, or nil
+//
+// Returns:
+//   - *report.Report: The run report, or nil if no ticker could be processed
+//   - error: Any error that occurred during processing, including more
+//     tickers failing than config.Report.MaxFailures allows
+func processTickerList(ctx context.Context, s *scraper.Scraper, logger *utils.Logger, config *utils.Config, tickers []utils.Ticker, state *runstate.State, m *metrics.Metrics) (*report.Report, error) {
+	startedAt := time.Now()
+	totalTickers := len(tickers)
+	workers := config.Scraper.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > totalTickers {
+		workers = totalTickers
+	}
+	logger.Info("Starting to process %d tickers with %d workers", totalTickers, workers)
+
+	// Open one tab per worker up front so failures surface before any work starts
+	tabs := make([]*scraper.Scraper, 0, workers)
+	for w := 0; w < workers; w++ {
+		tab, err := s.NewTab()
+		if err != nil {
+			logger.Error("Failed to open tab for worker %d: %v", w+1, err)
+			continue
+		}
+		tabs = append(tabs, tab)
+	}
+	if len(tabs) == 0 && totalTickers > 0 {
+		return nil, fmt.Errorf("no browser tabs available")
+	}
+
+	results := make([]tickerResult, totalTickers)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w, tab := range tabs {
+		wg.Add(1)
+		go func(worker int, tab *scraper.Scraper) {
+			defer wg.Done()
+			runWorker(ctx, worker, tab, logger, config, tickers, state, m, jobs, results)
+		}(w+1, tab)
+	}
+
+	for i := range tickers {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Close the tabs; their timings are already in the shared tracker
+	for _, tab := range tabs {
+		tab.CloseTab()
+	}
+
+	outcomes := make([]report.Ticker, 0, len(results))
+	for _, result := range results {
+		if result.timedOut {
+			logger.Error("%s: TIMED OUT after %v: %v", result.ticker.Symbol, result.duration.Round(time.Second), result.err)
+		} else if result.err != nil {
+			logger.Error("%s: failed after %v: %v", result.ticker.Symbol, result.duration.Round(time.Second), result.err)
+		} else {
+			logger.Info("%s: completed in %v", result.ticker.Symbol, result.duration.Round(time.Second))
+		}
+		outcomes = append(outcomes, result.report())
+	}
+
+	// Generate and log aggregate performance report
+	perfReport := s.GetPerformanceTracker().GenerateAggregateReport()
+	logger.Info("Aggregate Performance Report:\n%s", perfReport)
+
+	runReport := report.New(startedAt, outcomes)
+	if err := runReport.WriteJSON(config.Report.Path); err != nil {
+		logger.Error("Failed to write run report: %v", err)
+	} else {
+		logger.Info("Run report written to %s", config.Report.Path)
+	}
+	fmt.Println()
+	runReport.WriteTable(os.Stdout)
+
+	logger.Info("Completed processing %d tickers (%d failed, %d timed out)", totalTickers, runReport.Failed, runReport.TimedOut)
+	failed := runReport.Exceeds(config.Report.MaxFailures)
+	m.ObserveRun(runReport, failed)
+	if failed {
+		return runReport, fmt.Errorf("%d of %d tickers failed, more than the %d allowed", runReport.Failed, totalTickers, config.Report.MaxFailures)
+	}
+	return runReport, nil
+}
+
+// report converts the result to its run report entry.
+func (r tickerResult) report() report.Ticker {
+	entry := report.Ticker{
+		Ticker:          r.ticker.Symbol,
+		Sector:          r.ticker.Sector,
+		Name:            r.ticker.Name,
+		Outcome:         report.OutcomeOK,
+		RowsFetched:     r.stats.Fetched,
+		RowsAdded:       r.stats.Added,
+		RowsRevised:     r.stats.Revised,
+		Pages:           r.stats.Pages,
+		DurationSeconds: report.Seconds(r.duration),
+	}
+	if r.err != nil {
+		entry.Outcome = report.OutcomeFailed
+		if r.timedOut {
+			entry.Outcome = report.OutcomeTimedOut
+		}
+		entry.ErrorClass = scraper.ErrorClass(r.err)
+		entry.Error = r.err.Error()
+	}
+	return entry
+}
+
+// runWorker processes ticker indices from jobs on a single browser tab and
+// stores each outcome at the ticker's index in results, in the run state and in
+// the metrics.
+func runWorker(ctx context.Context, worker int, tab *scraper.Scraper, logger *utils.Logger, config *utils.Config, tickers []utils.Ticker, state *runstate.State, m *metrics.Metrics, jobs <-chan int, results []tickerResult) {
+	logger = logger.With("worker", worker)
+	waits := config.Scraper.Waits
+	processed := 0
+	var wait time.Duration
+	for i := range jobs {
+		ticker := tickers[i]
+
+		// Once the run deadline has passed, drain the remaining tickers
+		if err := ctx.Err(); err != nil {
+			results[i] = tickerResult{ticker: ticker, err: context.Cause(ctx), timedOut: scraper.IsTimeout(context.Cause(ctx))}
+			recordState(logger, state, ticker.Symbol, 0, results[i].err)
+			m.ObserveTicker(results[i].report())
+			continue
+		}
+
+		// Pause between tickers; the pause length depends on how the previous one went
+		if wait > 0 {
+			logger.Debug("Worker %d: waiting %v before next ticker", worker, wait)
+			sleepContext(ctx, wait)
+		}
+
+		// Refresh the tab every 5 tickers to keep memory usage in check
+		if processed > 0 && processed%5 == 0 {
+			logger.Debug("Worker %d: refreshing browser tab", worker)
+			if err := tab.RefreshTabContext(ctx); err != nil {
+				logger.Error("Worker %d: failed to refresh browser tab: %v", worker, err)
+				sleepContext(ctx, waits.AfterError.D())
+			}
+		}
+
+		logger.Info("Worker %d: processing ticker %d/%d: %s", worker, i+1, len(tickers), ticker.Symbol)
+		start := time.Now()
+		err := processSingleTicker(ctx, tab, logger, ticker)
+		results[i] = tickerResult{ticker: ticker, err: err, timedOut: scraper.IsTimeout(err), duration: time.Since(start), stats: tab.LastStats()}
+		recordState(logger, state, ticker.Symbol, results[i].stats.Pages, err)
+		m.ObserveTicker(results[i].report())
+		processed++
+
+		if err != nil {
+			logger.Error("Failed to process ticker %s: %v", ticker.Symbol, err)
+			// A timed-out or failed navigation leaves the tab in an unknown state
+			if scraper.IsTimeout(err) || strings.Contains(err.Error(), "context canceled") {
+				logger.Debug("Worker %d: navigation failed, refreshing browser tab", worker)
+				if err := tab.RefreshTabContext(ctx); err != nil {
+					logger.Error("Worker %d: failed to refresh browser tab: %v", worker, err)
+				}
+			}
+			wait = waits.AfterError.D()
+			continue
+		}
+
+		wait = waits.BetweenTickers.D()
+	}
+}
+
+// recordState saves the outcome of ticker to the run state, if there is one.
+// A state that cannot be saved is logged but does not stop the batch.
+func recordState(logger *utils.Logger, state *runstate.State, ticker string, lastPage int, err error) {
+	if state == nil {
+		return
+	}
+	if saveErr := state.Update(ticker, lastPage, err); saveErr != nil {
+		logger.Error("Failed to save run state for %s: %v", ticker, saveErr)
+	}
+}
+
+// prepareRunState returns the run state for a batch over tickers and the
+// tickers still to process. A new run starts with every ticker pending; with
+// resume, tickers already done are skipped, and with onlyFailed only the
+// tickers that failed in the recorded run are processed.
+func prepareRunState(logger *utils.Logger, path string, tickers []utils.Ticker, resume, onlyFailed bool) (*runstate.State, []utils.Ticker, error) {
+	if resume || onlyFailed {
+		state, err := runstate.Load(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load run state: %v", err)
+		}
+		if state != nil {
+			keep := make(map[string]bool)
+			for _, symbol := range state.Select(utils.Symbols(tickers), onlyFailed) {
+				keep[symbol] = true
+			}
+			var selected []utils.Ticker
+			for _, t := range tickers {
+				if keep[t.Symbol] {
+					selected = append(selected, t)
+				}
+			}
+			logger.Info("Continuing run started %s from %s: %d of %d tickers to process",
+				state.StartedAt.Format(time.RFC3339), path, len(selected), len(tickers))
+			return state, selected, state.Save()
+		}
+		if onlyFailed {
+			return nil, nil, fmt.Errorf("no run state found at %s", path)
+		}
+		logger.Info("No run state found at %s, starting a new run", path)
+	}
+
+	state := runstate.New(path, utils.Symbols(tickers))
+	return state, tickers, state.Save()
+}
+
+// sleepContext pauses for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"webscraper/internal/api"
+	"webscraper/internal/report"
+	"webscraper/internal/utils"
+)
+
+var serveCommand = &command{
+	name:    "serve",
+	summary: "Serve the HTTP API for scrape jobs and stored history",
+	help: `
+Stay running and serve the HTTP API on api.addr:
+
+  POST /api/scrapes                   enqueue a scrape: {"tickers": ["BBOB"]} or ?ticker=BBOB;
+                                      no tickers scrapes the ticker file
+  GET  /api/scrapes                   recent jobs, newest first
+  GET  /api/scrapes/{id}              status and run report of a job
+  GET  /api/tickers/{ticker}/history  stored history; ?from=YYYY-MM-DD&to=YYYY-MM-DD&format=json|jsonl|csv|parquet
+  GET  /healthz                       liveness check
+
+Jobs run one at a time on a single browser kept open by the server. The
+server stops on Ctrl+C or SIGTERM. Use "daemon -serve" to also scrape on a
+schedule.`,
+	run: runServeCommand,
+}
+
+func runServeCommand(fs *flag.FlagSet, args []string) error {
+	common := addCommonFlags(fs)
+	scrape := addScrapeFlags(fs)
+	tickerFile := fs.String("file", "", "CSV file of tickers scraped by jobs without tickers (default scraper.tickersFile)")
+	apiAddr := fs.String("addr", "", "Listen address of the HTTP API (e.g. 127.0.0.1:8080), overrides config")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	a, err := common.open(false)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	if *tickerFile == "" {
+		*tickerFile = a.config.Scraper.TickersFile
+	}
+	if *apiAddr != "" {
+		a.config.API.Addr = *apiAddr
+	}
+
+	ss, err := openSession(a, scrape)
+	if err != nil {
+		return a.fail("%v", err)
+	}
+	defer ss.Close()
+
+	apiServer, err := startAPI(ss, a.logger, a.config, *tickerFile)
+	if err != nil {
+		return a.fail("failed to start API server: %v", err)
+	}
+	defer apiServer.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	a.logger.Info("Stopping API server")
+	return nil
+}
+
+// startAPI starts the HTTP API on config.API.Addr. Scrape jobs run as batches
+// on the session's browser, one at a time and never alongside a scheduled
+// run; a job without tickers scrapes tickerFile, which also supplies the
+// sectors and names in run reports and history responses. History is read
+// from the session's store.
+//
+// Parameters:
+//   - ss: The browser session scraping the jobs
+//   - logger: Logger for tracking the process
+//   - config: Configuration providing the API address and run settings
+//   - tickerFile: Path to the CSV file of tickers scraped by jobs without tickers
+//
+// Returns:
+//   - *api.Server: The running server, to be closed on shutdown
+//   - error: Any error starting the server
+func startAPI(ss *session, logger *utils.Logger, config *utils.Config, tickerFile string) (*api.Server, error) {
+	run := func(ctx context.Context, symbols []string) (*report.Report, error) {
+		if len(symbols) > 0 {
+			tickers, err := lookupTickers(logger, tickerFile, symbols)
+			if err != nil {
+				return nil, err
+			}
+			return runBatch(ctx, ss.scraper, logger, config, tickers, ss.metrics)
+		}
+		listed, err := utils.ReadTickers(tickerFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CSV file %s: %v", tickerFile, err)
+		}
+		return runBatch(ctx, ss.scraper, logger, config, utils.EnabledTickers(listed), ss.metrics)
+	}
+
+	server := api.New(logger, ss.store, run, config.API.MaxQueued)
+	server.SetTickerFile(tickerFile)
+	server.SetCSVSchema(config.Storage.CSVSchema)
+	if err := server.Serve(config.API.Addr); err != nil {
+		return nil, err
+	}
+	logger.Info("Serving API at http://%s/api", config.API.Addr)
+	return server, nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"webscraper/internal/export"
+	"webscraper/internal/metrics"
+	"webscraper/internal/replay"
+	"webscraper/internal/scraper"
+	"webscraper/internal/storage"
+	"webscraper/internal/utils"
+
+	"github.com/chromedp/chromedp"
+)
+
+// scrapeFlags are accepted by the commands that scrape: scrape, daemon and
+// serve.
+type scrapeFlags struct {
+	fromDate      string
+	toDate        string
+	baseURL       string
+	replayDir     string
+	workers       int
+	tickerTimeout time.Duration
+	runTimeout    time.Duration
+	force         bool
+	exportFormats string
+	metricsAddr   string
+}
+
+func addScrapeFlags(fs *flag.FlagSet) *scrapeFlags {
+	f := &scrapeFlags{}
+	fs.StringVar(&f.fromDate, "from", "", "First trading date to fetch (YYYY-MM-DD), overrides config")
+	fs.StringVar(&f.toDate, "to", "", "Last trading date to fetch (YYYY-MM-DD), overrides config (default today)")
+	fs.StringVar(&f.baseURL, "base-url", "", "ISX portal root URL, overrides config")
+	fs.StringVar(&f.replayDir, "replay", "", "Serve saved portal pages from this fixture directory instead of the live site")
+	fs.IntVar(&f.workers, "workers", 0, "Number of concurrent browser tabs for batches, overrides config")
+	fs.DurationVar(&f.tickerTimeout, "ticker-timeout", 0, "Deadline for scraping a single ticker, overrides config (e.g. 90s)")
+	fs.DurationVar(&f.runTimeout, "run-timeout", 0, "Deadline for a whole batch, overrides config (e.g. 30m)")
+	fs.BoolVar(&f.force, "force", false, "Save even when the new history has fewer trading days than the saved one")
+	fs.StringVar(&f.exportFormats, "export", "", "Comma-separated formats to export after each ticker ("+strings.Join(export.Formats(), ", ")+"), overrides config")
+	fs.StringVar(&f.metricsAddr, "metrics-addr", "", "Serve Prometheus metrics at /metrics on this address while running (e.g. :9090), overrides config")
+	return f
+}
+
+// apply overrides the configuration with the flags that were given.
+func (f *scrapeFlags) apply(config *utils.Config) error {
+	// Command-line dates take precedence over the configured range
+	if f.fromDate != "" {
+		config.Scraper.FromDate = f.fromDate
+	}
+	if f.toDate != "" {
+		config.Scraper.ToDate = f.toDate
+	}
+	if _, _, err := utils.ResolveDateRange(config.Scraper.FromDate, config.Scraper.ToDate); err != nil {
+		return fmt.Errorf("invalid date range: %v", err)
+	}
+
+	if f.workers > 0 {
+		config.Scraper.Workers = f.workers
+	}
+	if f.tickerTimeout > 0 {
+		config.Scraper.Timeout = int(math.Ceil(f.tickerTimeout.Seconds()))
+	}
+	if f.runTimeout > 0 {
+		config.Scraper.RunTimeout = utils.Duration(f.runTimeout)
+	}
+	if f.baseURL != "" {
+		config.Scraper.BaseURL = f.baseURL
+	}
+	if f.exportFormats != "" {
+		config.Export.Formats = strings.Split(f.exportFormats, ",")
+	}
+	if f.metricsAddr != "" {
+		config.Metrics.Addr = f.metricsAddr
+	}
+	return nil
+}
+
+// session is a running browser ready to scrape, with the store it saves to
+// and the metrics it records.
+type session struct {
+	scraper *scraper.Scraper
+	store   storage.Store
+	metrics *metrics.Metrics
+
+	// closers release the session's resources, in reverse order
+	closers []func()
+}
+
+// openSession applies the scrape flags, starts the browser, runs the
+// preflight checks and opens the store. Close the session when done.
+func openSession(a *app, f *scrapeFlags) (*session, error) {
+	logger, config := a.logger, a.config
+	if err := f.apply(config); err != nil {
+		return nil, err
+	}
+	exporters, err := export.ParseFormats(config.Export.Formats)
+	if err != nil {
+		return nil, fmt.Errorf("invalid export formats: %v", err)
+	}
+	for i, e := range exporters {
+		exporters[i] = export.WithCSVSchema(e, config.Storage.CSVSchema)
+	}
+
+	ss := &session{}
+	ok := false
+	defer func() {
+		if !ok {
+			ss.Close()
+		}
+	}()
+
+	// Point the scraper at a local replay server when fixtures are given
+	if f.replayDir != "" {
+		replayServer, err := replay.NewServer(f.replayDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start replay server: %v", err)
+		}
+		ss.closers = append(ss.closers, replayServer.Close)
+
+		config.Scraper.BaseURL = replayServer.BaseURL()
+		logger.Info("Replaying portal fixtures from %s at %s", f.replayDir, config.Scraper.BaseURL)
+	}
+
+	s, cancel, err := initializeScraper(logger, config)
+	ss.closers = append(ss.closers, func() {
+		logger.Info("Starting cleanup")
+		if s != nil {
+			s.Close() // Close the scraper first
+		}
+		cancel() // Then cancel the context
+		logger.Info("Cleanup completed")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize scraper: %v", err)
+	}
+	ss.scraper = s
+
+	if err := s.PreflightCheck(); err != nil {
+		return nil, fmt.Errorf("preflight check failed: %v", err)
+	}
+
+	storeOptions := storage.Options{Backups: config.Storage.Backups, Force: f.force, CSVSchema: config.Storage.CSVSchema}
+	store, err := storage.Open(config.Storage.Store, storeOptions, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %v", err)
+	}
+	ss.closers = append(ss.closers, func() { store.Close() })
+	ss.store = store
+	s.SetStore(store)
+	s.SetExporters(config.Export.Dir, exporters)
+
+	// Expose metrics while the session runs when an address is configured
+	if config.Metrics.Addr != "" {
+		ss.metrics = metrics.New()
+		server, err := ss.metrics.Serve(config.Metrics.Addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start metrics endpoint: %v", err)
+		}
+		ss.closers = append(ss.closers, func() { server.Close() })
+		s.SetMetrics(ss.metrics)
+		logger.Info("Serving metrics at http://%s%s", config.Metrics.Addr, metrics.Path)
+	}
+
+	ok = true
+	return ss, nil
+}
+
+// Close stops the metrics endpoint, closes the store and the browser, and
+// stops the replay server, in that order.
+func (ss *session) Close() {
+	for i := len(ss.closers) - 1; i >= 0; i-- {
+		ss.closers[i]()
+	}
+	ss.closers = nil
+}
+
+// initializeScraper sets up the Chrome browser and creates necessary directories.
+// It configures the browser with Arabic language support and creates the screenshots directory.
+//
+// Parameters:
+//   - logger: Logger for tracking the initialization process
+//   - config: Configuration for the scraper
+//
+// Returns:
+//   - *scraper.Scraper: Configured scraper instance
+//   - context.CancelFunc: Function to cancel the browser context
+//   - error: Any error that occurred during initialization
+func initializeScraper(logger *utils.Logger, config *utils.Config) (*scraper.Scraper, context.CancelFunc, error) {
+	logger.Debug("Initializing Chrome with Arabic support")
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("lang", "ar"),
+		chromedp.Flag("disable-gpu", true),
+		chromedp.Flag("no-sandbox", true),
+		chromedp.Flag("disable-setuid-sandbox", true),
+		chromedp.NoSandbox,
+		chromedp.Flag("headless", config.Scraper.Browser.Headless),
+		chromedp.Flag("start-maximized", true),
+		chromedp.Flag("enable-logging", config.Scraper.Browser.Debug),
+		chromedp.Flag("v", "1"),
+	)
+
+	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
+	ctx, _ := chromedp.NewContext(allocCtx, chromedp.WithLogf(logger.Debug))
+
+	// Test browser launch
+	if err := chromedp.Run(ctx, chromedp.Navigate("about:blank")); err != nil {
+		logger.Error("Failed to launch browser: %v", err)
+		return nil, cancel, err
+	}
+
+	// Create screenshots directory
+	if err := os.MkdirAll(filepath.Join(config.Logging.Dir, "screenshots"), 0755); err != nil {
+		logger.Error("Failed to create screenshots directory: %v", err)
+		return nil, cancel, err
+	}
+
+	return scraper.NewScraper(logger, ctx, cancel, config), cancel, nil
+}
@@ -1,9 +1,17 @@
 #!/bin/bash
-if [ -n "$TICKER" ]; then
-    ./webscraper -ticker "$TICKER"
+if [ -n "$DAEMON" ]; then
+    args=()
+    # Inside the container the API must listen on all interfaces to be
+    # reachable through the published port
+    [ -n "$SERVE" ] && args+=(-serve -addr "${API_ADDR:-:8080}")
+    exec ./webscraper daemon "${args[@]}"
+elif [ -n "$SERVE" ]; then
+    exec ./webscraper serve -addr "${API_ADDR:-:8080}"
+elif [ -n "$TICKER" ]; then
+    ./webscraper scrape "$TICKER"
 elif [ -n "$FILE" ]; then
-    ./webscraper -file "$FILE"
+    ./webscraper scrape -file "$FILE"
 else
-    echo "Please provide either TICKER or FILE environment variable"
+    echo "Please provide either TICKER, FILE, DAEMON or SERVE environment variable"
     exit 1
-fi 
\ No newline at end of file
+fi
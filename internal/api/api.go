@@ -0,0 +1,293 @@
+// Package api serves an HTTP interface to the scraper for other services:
+// enqueueing scrapes of single tickers or whole batches, polling their status,
+// and reading the stored price history.
+//
+// Endpoints:
+//
+//	POST /api/scrapes                       enqueue a scrape, body {"tickers": ["BBOB"]} or ?ticker=BBOB; no tickers scrapes the ticker file
+//	GET  /api/scrapes                       recent jobs, newest first
+//	GET  /api/scrapes/{id}                  status and report of one job
+//	GET  /api/tickers/{ticker}/history      stored history; ?from=YYYY-MM-DD&to=YYYY-MM-DD&format=json|jsonl|csv|parquet
+//	GET  /healthz                           liveness check
+//
+// Jobs run one at a time on the scraper's browser. History is read from the
+// same store the scraper saves to.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"webscraper/internal/export"
+	"webscraper/internal/storage"
+	"webscraper/internal/utils"
+	"webscraper/models"
+)
+
+// DefaultMaxQueued is the number of jobs that may wait for the worker when
+// none is configured.
+const DefaultMaxQueued = 20
+
+// contentTypes maps export formats to the Content-Type of their responses.
+var contentTypes = map[string]string{
+	"csv":     "text/csv; charset=utf-8",
+	"json":    "application/json",
+	"jsonl":   "application/x-ndjson",
+	"parquet": "application/vnd.apache.parquet",
+}
+
+// Server is the HTTP API. Create it with New and start it with Serve.
+type Server struct {
+	logger *utils.Logger
+	store  storage.Store
+	run    Runner
+	http   *http.Server
+	// tickerFile, if set, supplies the sector and name of exported history
+	tickerFile string
+	// csvSchema is the CSV schema version of csv history responses
+	csvSchema int
+
+	// ctx bounds running jobs and is cancelled by Close
+	ctx    context.Context
+	cancel context.CancelFunc
+	queue  chan *Job
+	done   chan struct{}
+
+	mu     sync.Mutex
+	jobs   map[string]*Job
+	order  []string // job IDs, oldest first
+	nextID int
+}
+
+// New creates an API server reading history from store and running scrape
+// jobs with run. At most maxQueued jobs wait for the worker; further requests
+// are rejected until the queue drains.
+func New(logger *utils.Logger, store storage.Store, run Runner, maxQueued int) *Server {
+	if maxQueued < 1 {
+		maxQueued = DefaultMaxQueued
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		logger: logger.With("component", "api"),
+		store:  store,
+		run:    run,
+		ctx:    ctx,
+		cancel: cancel,
+		queue:  make(chan *Job, maxQueued),
+		done:   make(chan struct{}),
+		jobs:   make(map[string]*Job),
+	}
+	s.http = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// SetTickerFile sets the ticker file whose sectors and names are included in
+// history responses, in the formats with room for them.
+func (s *Server) SetTickerFile(path string) {
+	s.tickerFile = path
+}
+
+// SetCSVSchema sets the CSV schema version of history responses in csv
+// format; zero uses models.SchemaVersion.
+func (s *Server) SetCSVSchema(version int) {
+	s.csvSchema = version
+}
+
+// Handler returns the HTTP handler serving the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/scrapes", s.handleEnqueue)
+	mux.HandleFunc("GET /api/scrapes", s.handleJobs)
+	mux.HandleFunc("GET /api/scrapes/{id}", s.handleJob)
+	mux.HandleFunc("GET /api/tickers/{ticker}/history", s.handleHistory)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return mux
+}
+
+// Serve starts the job worker and serves the API on addr, e.g.
+// "127.0.0.1:8080", in the background. The API has no authentication, so addr
+// should be a loopback or otherwise trusted address.
+func (s *Server) Serve(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", addr, err)
+	}
+	go s.work()
+	go func() {
+		if err := s.http.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("API server stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Close stops accepting requests, cancels the running job and waits for it
+// to return.
+func (s *Server) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := s.http.Shutdown(ctx)
+	s.cancel()
+	<-s.done
+	return err
+}
+
+// scrapeRequest is the body of POST /api/scrapes.
+type scrapeRequest struct {
+	Tickers []string `json:"tickers"`
+}
+
+func (s *Server) handleEnqueue(w http.ResponseWriter, r *http.Request) {
+	var request scrapeRequest
+	if r.Body != nil {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+			return
+		}
+	}
+	request.Tickers = append(request.Tickers, r.URL.Query()["ticker"]...)
+
+	tickers := make([]string, 0, len(request.Tickers))
+	seen := make(map[string]bool)
+	for _, ticker := range request.Tickers {
+		ticker, err := normalizeTicker(ticker)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if !seen[ticker] {
+			seen[ticker] = true
+			tickers = append(tickers, ticker)
+		}
+	}
+
+	job, err := s.enqueue(tickers)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	w.Header().Set("Location", "/api/scrapes/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.jobList())
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.job(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no job %q", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	ticker, err := normalizeTicker(r.PathValue("ticker"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	query := r.URL.Query()
+	from, err := parseDate(query.Get("from"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid from date: %v", err))
+		return
+	}
+	to, err := parseDate(query.Get("to"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid to date: %v", err))
+		return
+	}
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	exporter, err := export.New(format)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	exporter = export.WithCSVSchema(exporter, s.csvSchema)
+
+	data, err := s.store.Load(ticker)
+	if err != nil {
+		s.logger.Error("Failed to load history of %s: %v", ticker, err)
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to load history of %s", ticker))
+		return
+	}
+	if len(data) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no stored history for %s", ticker))
+		return
+	}
+
+	data = models.FilterDates(data, from, to)
+	w.Header().Set("Content-Type", contentTypes[exporter.Name()])
+	if exporter.Name() == "parquet" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", ticker+"_data"+exporter.Ext()))
+	}
+	if err := exporter.Export(w, s.company(ticker), data); err != nil {
+		s.logger.Error("Failed to write history of %s as %s: %v", ticker, exporter.Name(), err)
+	}
+}
+
+// company returns ticker's entry in the ticker file, or just its symbol if
+// it has none.
+func (s *Server) company(ticker string) models.Company {
+	if s.tickerFile == "" {
+		return models.Company{Symbol: ticker}
+	}
+	tickers, err := utils.ReadTickers(s.tickerFile)
+	if err != nil {
+		s.logger.Debug("Failed to read ticker file %s: %v", s.tickerFile, err)
+	}
+	for _, t := range tickers {
+		if t.Symbol == ticker {
+			return t.Company
+		}
+	}
+	return models.Company{Symbol: ticker}
+}
+
+// normalizeTicker upper-cases ticker and checks that it is a valid symbol.
+func normalizeTicker(ticker string) (string, error) {
+	ticker = strings.ToUpper(strings.TrimSpace(ticker))
+	if !models.ValidSymbol(ticker) {
+		return "", fmt.Errorf("invalid ticker %q", ticker)
+	}
+	return ticker, nil
+}
+
+// parseDate parses an optional YYYY-MM-DD query parameter.
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(utils.DateLayout, value)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+	"webscraper/internal/report"
+)
+
+// JobStatus is the progress of a scrape job.
+type JobStatus string
+
+const (
+	JobQueued  JobStatus = "queued"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+// maxJobs is the number of jobs remembered for status queries; the oldest
+// finished jobs are forgotten first.
+const maxJobs = 100
+
+// errQueueFull is returned when more jobs are waiting than allowed.
+var errQueueFull = errors.New("too many scrape jobs queued")
+
+// Runner scrapes a batch of tickers and returns its report. An empty list
+// scrapes the configured ticker file. The report may be nil when the batch
+// failed before any ticker was processed.
+type Runner func(ctx context.Context, tickers []string) (*report.Report, error)
+
+// Job is a requested scrape of one or more tickers.
+type Job struct {
+	ID         string         `json:"id"`
+	Tickers    []string       `json:"tickers"` // empty for the configured ticker file
+	Status     JobStatus      `json:"status"`
+	CreatedAt  time.Time      `json:"created_at"`
+	StartedAt  *time.Time     `json:"started_at,omitempty"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	Report     *report.Report `json:"report,omitempty"`
+}
+
+// enqueue records a new job for tickers and queues it for the worker.
+func (s *Server) enqueue(tickers []string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	job := &Job{
+		ID:        strconv.Itoa(s.nextID),
+		Tickers:   tickers,
+		Status:    JobQueued,
+		CreatedAt: time.Now(),
+	}
+	select {
+	case s.queue <- job:
+	default:
+		return Job{}, errQueueFull
+	}
+
+	s.jobs[job.ID] = job
+	s.order = append(s.order, job.ID)
+	s.forgetOldJobs()
+	return *job, nil
+}
+
+// forgetOldJobs drops the oldest finished jobs beyond maxJobs. The caller
+// must hold s.mu.
+func (s *Server) forgetOldJobs() {
+	excess := len(s.order) - maxJobs
+	kept := s.order[:0]
+	for _, id := range s.order {
+		job := s.jobs[id]
+		if excess > 0 && (job.Status == JobDone || job.Status == JobFailed) {
+			delete(s.jobs, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.order = kept
+}
+
+// job returns a copy of the job with the given ID.
+func (s *Server) job(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// jobList returns copies of the remembered jobs, newest first.
+func (s *Server) jobList() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]Job, 0, len(s.order))
+	for i := len(s.order) - 1; i >= 0; i-- {
+		jobs = append(jobs, *s.jobs[s.order[i]])
+	}
+	return jobs
+}
+
+// work runs queued jobs one at a time until the server is closed.
+func (s *Server) work() {
+	defer close(s.done)
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case job := <-s.queue:
+			s.runJob(job)
+		}
+	}
+}
+
+func (s *Server) runJob(job *Job) {
+	started := time.Now()
+	s.mu.Lock()
+	job.Status = JobRunning
+	job.StartedAt = &started
+	tickers := job.Tickers
+	s.mu.Unlock()
+
+	logger := s.logger.With("job", job.ID)
+	logger.Info("Starting scrape job for %s", describeTickers(tickers))
+	runReport, err := s.run(s.ctx, tickers)
+
+	finished := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job.FinishedAt = &finished
+	job.Report = runReport
+	job.Status = JobDone
+	if err != nil {
+		job.Status = JobFailed
+		job.Error = err.Error()
+		logger.Error("Scrape job failed: %v", err)
+		return
+	}
+	logger.Info("Scrape job finished in %v", finished.Sub(started).Round(time.Second))
+}
+
+func describeTickers(tickers []string) string {
+	switch len(tickers) {
+	case 0:
+		return "the ticker file"
+	case 1:
+		return tickers[0]
+	}
+	return strconv.Itoa(len(tickers)) + " tickers"
+}
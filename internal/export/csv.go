@@ -0,0 +1,25 @@
+package export
+
+import (
+	"io"
+	"webscraper/models"
+)
+
+// CSV writes the versioned CSV schema also used by the CSV store. Its columns
+// are fixed, so it does not carry the company's sector and name.
+type CSV struct {
+	// Schema is the models CSV schema version written; zero writes
+	// models.SchemaVersion.
+	Schema int
+}
+
+func (CSV) Name() string { return "csv" }
+
+func (CSV) Ext() string { return ".csv" }
+
+func (c CSV) Export(w io.Writer, company models.Company, data []models.StockData) error {
+	if c.Schema == 0 {
+		return models.WriteCSV(w, data)
+	}
+	return models.WriteCSVVersion(w, data, c.Schema)
+}
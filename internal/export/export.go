@@ -0,0 +1,108 @@
+// Package export writes price history to files in formats meant for
+// analysis tools: CSV, JSON, JSON Lines and Parquet. Unlike the CSV schema in
+// the models package, the JSON and Parquet formats carry typed columns:
+// a date, prices as floating point numbers and counts as integers.
+package export
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"webscraper/internal/utils"
+	"webscraper/models"
+)
+
+// Exporter writes the price history of one ticker in a single file format.
+type Exporter interface {
+	// Name is the format name used in config and flags, e.g. "jsonl".
+	Name() string
+	// Ext is the file extension written, including the dot.
+	Ext() string
+	// Export writes company's history data, newest first, to w. Formats
+	// with room for it carry the company's sector and name as well.
+	Export(w io.Writer, company models.Company, data []models.StockData) error
+}
+
+// exporters lists the supported formats by name.
+var exporters = map[string]Exporter{
+	"csv":     CSV{},
+	"json":    JSON{},
+	"jsonl":   JSONL{},
+	"parquet": Parquet{},
+}
+
+// Formats returns the names of the supported formats, sorted.
+func Formats() []string {
+	names := make([]string, 0, len(exporters))
+	for name := range exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns the exporter for the named format.
+func New(format string) (Exporter, error) {
+	exporter, ok := exporters[strings.ToLower(strings.TrimSpace(format))]
+	if !ok {
+		return nil, fmt.Errorf("unknown export format %q (supported: %s)", format, strings.Join(Formats(), ", "))
+	}
+	return exporter, nil
+}
+
+// ParseFormats returns the exporters for a list of format names, such as the
+// comma-separated value of the -export flag. Empty names are ignored.
+func ParseFormats(formats []string) ([]Exporter, error) {
+	var result []Exporter
+	seen := make(map[string]bool)
+	for _, format := range formats {
+		if strings.TrimSpace(format) == "" {
+			continue
+		}
+		exporter, err := New(format)
+		if err != nil {
+			return nil, err
+		}
+		if seen[exporter.Name()] {
+			continue
+		}
+		seen[exporter.Name()] = true
+		result = append(result, exporter)
+	}
+	return result, nil
+}
+
+// WithCSVSchema returns e set to write the given CSV schema version if it is
+// the CSV exporter, and e unchanged otherwise.
+func WithCSVSchema(e Exporter, version int) Exporter {
+	if _, ok := e.(CSV); ok {
+		return CSV{Schema: version}
+	}
+	return e
+}
+
+// Path returns the file the exporter writes ticker's history to in dir.
+func Path(e Exporter, dir, ticker string) string {
+	return filepath.Join(dir, fmt.Sprintf("%s_data%s", ticker, e.Ext()))
+}
+
+// WriteFile exports company's history to its file in dir and returns the
+// path. The file is replaced atomically, so readers never see a partial
+// export.
+func WriteFile(e Exporter, dir string, company models.Company, data []models.StockData) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create export directory: %v", err)
+	}
+
+	path := Path(e, dir, company.Symbol)
+	err := utils.WriteFileAtomic(path, 0, func(w io.Writer) error {
+		return e.Export(w, company, data)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,25 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"webscraper/models"
+)
+
+// JSON writes a single JSON array with one object per trading day, laid out
+// like the JSON Lines records.
+type JSON struct{}
+
+func (JSON) Name() string { return "json" }
+
+func (JSON) Ext() string { return ".json" }
+
+func (JSON) Export(w io.Writer, company models.Company, data []models.StockData) error {
+	rows := make([]jsonRow, 0, len(data))
+	for _, record := range data {
+		rows = append(rows, newJSONRow(company, record))
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
@@ -0,0 +1,61 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"webscraper/models"
+)
+
+// JSONL writes one JSON object per trading day.
+type JSONL struct{}
+
+// jsonRow is the typed layout of a JSON or JSON Lines record.
+type jsonRow struct {
+	Ticker      string  `json:"ticker"`
+	Sector      string  `json:"sector,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	Date        string  `json:"date"`
+	Open        float64 `json:"open"`
+	High        float64 `json:"high"`
+	Low         float64 `json:"low"`
+	Close       float64 `json:"close"`
+	Change      float64 `json:"change"`
+	ChangePct   float64 `json:"change_pct"`
+	Volume      int64   `json:"volume"`
+	TotalShares int64   `json:"total_shares"`
+	Trades      int64   `json:"trades"`
+}
+
+func (JSONL) Name() string { return "jsonl" }
+
+func (JSONL) Ext() string { return ".jsonl" }
+
+func (JSONL) Export(w io.Writer, company models.Company, data []models.StockData) error {
+	buf := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buf)
+	for _, record := range data {
+		if err := encoder.Encode(newJSONRow(company, record)); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
+
+func newJSONRow(company models.Company, record models.StockData) jsonRow {
+	return jsonRow{
+		Ticker:      company.Symbol,
+		Sector:      company.Sector,
+		Name:        company.Name,
+		Date:        record.Date.Format(models.DateLayout),
+		Open:        record.OpenPrice.Float64(),
+		High:        record.HighPrice.Float64(),
+		Low:         record.LowPrice.Float64(),
+		Close:       record.ClosePrice.Float64(),
+		Change:      record.Change.Float64(),
+		ChangePct:   record.ChangePerc,
+		Volume:      record.Volume,
+		TotalShares: record.TotalShares,
+		Trades:      record.NumTrades,
+	}
+}
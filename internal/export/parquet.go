@@ -0,0 +1,81 @@
+package export
+
+import (
+	"io"
+	"time"
+	"webscraper/models"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// Parquet writes a Snappy-compressed Parquet file with one row per trading
+// day. The ticker, sector and name are also recorded in the file's key-value
+// metadata.
+type Parquet struct{}
+
+// parquetRow is the typed layout of a Parquet row. Dates use the DATE logical
+// type, which pandas and DuckDB read as a date column.
+type parquetRow struct {
+	Ticker      string  `parquet:"name=ticker, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Date        int32   `parquet:"name=date, type=INT32, convertedtype=DATE"`
+	Open        float64 `parquet:"name=open, type=DOUBLE"`
+	High        float64 `parquet:"name=high, type=DOUBLE"`
+	Low         float64 `parquet:"name=low, type=DOUBLE"`
+	Close       float64 `parquet:"name=close, type=DOUBLE"`
+	Change      float64 `parquet:"name=change, type=DOUBLE"`
+	ChangePct   float64 `parquet:"name=change_pct, type=DOUBLE"`
+	Volume      int64   `parquet:"name=volume, type=INT64"`
+	TotalShares int64   `parquet:"name=total_shares, type=INT64"`
+	Trades      int64   `parquet:"name=trades, type=INT64"`
+}
+
+// parquetParallelism is the number of goroutines marshalling rows; a
+// ticker's history is small enough that one suffices.
+const parquetParallelism = 1
+
+func (Parquet) Name() string { return "parquet" }
+
+func (Parquet) Ext() string { return ".parquet" }
+
+func (Parquet) Export(w io.Writer, company models.Company, data []models.StockData) error {
+	pw, err := writer.NewParquetWriterFromWriter(w, new(parquetRow), parquetParallelism)
+	if err != nil {
+		return err
+	}
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+
+	for _, record := range data {
+		row := parquetRow{
+			Ticker:      company.Symbol,
+			Date:        daysSinceEpoch(record.Date),
+			Open:        record.OpenPrice.Float64(),
+			High:        record.HighPrice.Float64(),
+			Low:         record.LowPrice.Float64(),
+			Close:       record.ClosePrice.Float64(),
+			Change:      record.Change.Float64(),
+			ChangePct:   record.ChangePerc,
+			Volume:      record.Volume,
+			TotalShares: record.TotalShares,
+			Trades:      record.NumTrades,
+		}
+		if err := pw.Write(row); err != nil {
+			return err
+		}
+	}
+	for _, kv := range [][2]string{{"ticker", company.Symbol}, {"sector", company.Sector}, {"name", company.Name}} {
+		if kv[1] == "" {
+			continue
+		}
+		value := kv[1]
+		pw.Footer.KeyValueMetadata = append(pw.Footer.KeyValueMetadata, &parquet.KeyValue{Key: kv[0], Value: &value})
+	}
+	return pw.WriteStop()
+}
+
+// daysSinceEpoch converts a trading date to the Parquet DATE representation.
+func daysSinceEpoch(t time.Time) int32 {
+	y, m, d := t.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return int32(day.Unix() / 86400)
+}
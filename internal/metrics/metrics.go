@@ -0,0 +1,217 @@
+// Package metrics exports the progress of scraping runs in the Prometheus text
+// format, so runs can be monitored and graphed in Grafana. Metrics are only
+// collected when an endpoint is configured; every method is a no-op on a nil
+// *Metrics.
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+	"webscraper/internal/report"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Path is the URL path the metrics are served under.
+const Path = "/metrics"
+
+// namespace prefixes the name of every metric.
+const namespace = "webscraper"
+
+// Metrics holds the collectors updated while scraping.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	tickers       *prometheus.CounterVec
+	pages         *prometheus.CounterVec
+	rowsFetched   *prometheus.CounterVec
+	rowsAdded     *prometheus.CounterVec
+	rowsRevised   *prometheus.CounterVec
+	tickerSeconds *prometheus.HistogramVec
+	stepSeconds   *prometheus.HistogramVec
+	refreshes     *prometheus.CounterVec
+	retries       *prometheus.CounterVec
+
+	runs           *prometheus.CounterVec
+	lastRunEnd     prometheus.Gauge
+	lastRunSeconds prometheus.Gauge
+	lastRunFailed  prometheus.Gauge
+	nextRun        prometheus.Gauge
+}
+
+// New creates the scraper's metrics in a registry of their own, together with
+// the standard Go runtime and process metrics.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		tickers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tickers_processed_total",
+			Help:      "Tickers processed, by outcome (ok, failed or timed_out).",
+		}, []string{"ticker", "outcome"}),
+		pages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "pages_scraped_total",
+			Help:      "History pages extracted from the portal.",
+		}, []string{"ticker"}),
+		rowsFetched: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rows_extracted_total",
+			Help:      "Price rows read from the portal.",
+		}, []string{"ticker"}),
+		rowsAdded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rows_added_total",
+			Help:      "Trading days added to the saved history.",
+		}, []string{"ticker"}),
+		rowsRevised: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rows_revised_total",
+			Help:      "Saved trading days whose values changed on the portal.",
+		}, []string{"ticker"}),
+		tickerSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "ticker_duration_seconds",
+			Help:      "Time taken to scrape and save a ticker.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+		}, []string{"ticker"}),
+		stepSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "step_duration_seconds",
+			Help:      "Time taken by each scraping step, as timed by the performance tracker.",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+		}, []string{"ticker", "step"}),
+		refreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "browser_refreshes_total",
+			Help:      "Browser tabs replaced, by the ticker the tab was last used for.",
+		}, []string{"ticker"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retries_total",
+			Help:      "Scraping steps retried after a failure.",
+		}, []string{"ticker"}),
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "runs_total",
+			Help:      "Batch runs completed, by result (ok or failed).",
+		}, []string{"result"}),
+		lastRunEnd: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_run_timestamp_seconds",
+			Help:      "Unix time the last batch run finished.",
+		}),
+		lastRunSeconds: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_run_duration_seconds",
+			Help:      "Duration of the last batch run.",
+		}),
+		lastRunFailed: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_run_failed_tickers",
+			Help:      "Tickers that failed in the last batch run.",
+		}),
+		nextRun: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "next_run_timestamp_seconds",
+			Help:      "Unix time the daemon starts its next scheduled run.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.tickers, m.pages, m.rowsFetched, m.rowsAdded, m.rowsRevised,
+		m.tickerSeconds, m.stepSeconds, m.refreshes, m.retries,
+		m.runs, m.lastRunEnd, m.lastRunSeconds, m.lastRunFailed, m.nextRun,
+	)
+	return m
+}
+
+// Handler returns the HTTP handler serving the metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Serve starts serving the metrics at Path on addr, e.g. ":9090", in the
+// background. Close the returned server to stop it.
+func (m *Metrics) Serve(addr string) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %v", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(Path, m.Handler())
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go server.Serve(listener)
+	return server, nil
+}
+
+// ObserveTicker records the outcome of one ticker of a run.
+func (m *Metrics) ObserveTicker(t report.Ticker) {
+	if m == nil {
+		return
+	}
+	m.tickers.WithLabelValues(t.Ticker, t.Outcome).Inc()
+	m.pages.WithLabelValues(t.Ticker).Add(float64(t.Pages))
+	m.rowsFetched.WithLabelValues(t.Ticker).Add(float64(t.RowsFetched))
+	m.rowsAdded.WithLabelValues(t.Ticker).Add(float64(t.RowsAdded))
+	m.rowsRevised.WithLabelValues(t.Ticker).Add(float64(t.RowsRevised))
+	m.tickerSeconds.WithLabelValues(t.Ticker).Observe(t.DurationSeconds)
+}
+
+// ObserveRun records the summary of a finished batch run; failed reports
+// whether the run as a whole failed.
+func (m *Metrics) ObserveRun(r *report.Report, failed bool) {
+	if m == nil {
+		return
+	}
+	result := "ok"
+	if failed {
+		result = "failed"
+	}
+	m.runs.WithLabelValues(result).Inc()
+	m.lastRunEnd.Set(float64(r.FinishedAt.Unix()))
+	m.lastRunSeconds.Set(r.DurationSeconds)
+	m.lastRunFailed.Set(float64(r.Failed))
+}
+
+// SetNextRun records when the daemon's next scheduled run starts.
+func (m *Metrics) SetNextRun(t time.Time) {
+	if m == nil {
+		return
+	}
+	m.nextRun.Set(float64(t.Unix()))
+}
+
+// ObserveStep records the duration of a scraping step of ticker.
+func (m *Metrics) ObserveStep(ticker, step string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.stepSeconds.WithLabelValues(ticker, step).Observe(d.Seconds())
+}
+
+// BrowserRefreshed counts a browser tab replaced while working on ticker.
+func (m *Metrics) BrowserRefreshed(ticker string) {
+	if m == nil {
+		return
+	}
+	m.refreshes.WithLabelValues(ticker).Inc()
+}
+
+// Retried counts a retry of a scraping step of ticker.
+func (m *Metrics) Retried(ticker string) {
+	if m == nil {
+		return
+	}
+	m.retries.WithLabelValues(ticker).Inc()
+}
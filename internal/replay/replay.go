@@ -0,0 +1,167 @@
+// Package replay serves saved ISX portal pages from disk so the scraper can be
+// exercised without reaching isx-iq.net.
+//
+// A fixture directory is laid out as follows:
+//
+//	<dir>/companyprofilecontainer.html                       shared profile page
+//	<dir>/<TICKER>/companyprofilecontainer.html              optional per-ticker profile page
+//	<dir>/<TICKER>/companyperformancehistoryfilter_<N>.html  history table for page N
+//	<dir>/listedCompanies.html                               optional listed-companies page
+//
+// The profile page must provide the portal's doAjax function, the date filter
+// form and an element with id "ajxDspId" that receives the history pages.
+package replay
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PortalPath is the URL path under which the portal pages are served, matching
+// the path of the live site.
+const PortalPath = "/isxportal/portal"
+
+// pageParam is the display-tag query parameter carrying the history page number.
+const pageParam = "d-6716032-p"
+
+// tickerPattern matches the ticker codes served; anything else could name a
+// path outside the fixture directory.
+var tickerPattern = regexp.MustCompile(`^[A-Z0-9]+$`)
+
+// Request records a single page request received by the server.
+type Request struct {
+	Page   string
+	Ticker string
+	Number int
+	Query  string
+}
+
+// Server is a local HTTP server replaying fixture pages.
+type Server struct {
+	*httptest.Server
+
+	dir      string
+	mu       sync.Mutex
+	requests []Request
+}
+
+// NewServer starts a replay server for the fixtures in dir.
+func NewServer(dir string) (*Server, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open fixture directory: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("fixture path %s is not a directory", dir)
+	}
+
+	s := &Server{dir: dir}
+	mux := http.NewServeMux()
+	mux.HandleFunc(PortalPath+"/companyprofilecontainer.html", s.handleProfile)
+	mux.HandleFunc(PortalPath+"/companyperformancehistoryfilter.html", s.handleHistory)
+	mux.HandleFunc(PortalPath+"/listedCompanies.html", s.handleCompanies)
+	s.Server = httptest.NewServer(mux)
+
+	return s, nil
+}
+
+// BaseURL returns the portal root to configure as the scraper's base URL.
+func (s *Server) BaseURL() string {
+	return s.URL + PortalPath
+}
+
+// Requests returns the page requests received so far, in arrival order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests := make([]Request, len(s.requests))
+	copy(requests, s.requests)
+	return requests
+}
+
+func (s *Server) handleProfile(w http.ResponseWriter, r *http.Request) {
+	ticker := tickerFromRequest(r)
+	s.record(Request{Page: "profile", Ticker: ticker, Query: r.URL.RawQuery})
+	if ticker != "" && !tickerPattern.MatchString(ticker) {
+		http.Error(w, fmt.Sprintf("invalid companyCode %q", ticker), http.StatusBadRequest)
+		return
+	}
+
+	candidates := []string{
+		filepath.Join(s.dir, ticker, "companyprofilecontainer.html"),
+		filepath.Join(s.dir, "companyprofilecontainer.html"),
+	}
+	if ticker == "" {
+		candidates = candidates[1:]
+	}
+	s.serveFirst(w, r, candidates)
+}
+
+func (s *Server) handleCompanies(w http.ResponseWriter, r *http.Request) {
+	s.record(Request{Page: "companies", Query: r.URL.RawQuery})
+	s.serveFirst(w, r, []string{filepath.Join(s.dir, "listedCompanies.html")})
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ticker := tickerFromRequest(r)
+	page := 1
+	if value := r.Form.Get(pageParam); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			http.Error(w, fmt.Sprintf("invalid page number %q", value), http.StatusBadRequest)
+			return
+		}
+		page = n
+	}
+	s.record(Request{Page: "history", Ticker: ticker, Number: page, Query: r.Form.Encode()})
+
+	if ticker == "" {
+		http.Error(w, "missing companyCode", http.StatusBadRequest)
+		return
+	}
+	if !tickerPattern.MatchString(ticker) {
+		http.Error(w, fmt.Sprintf("invalid companyCode %q", ticker), http.StatusBadRequest)
+		return
+	}
+	name := fmt.Sprintf("companyperformancehistoryfilter_%d.html", page)
+	s.serveFirst(w, r, []string{filepath.Join(s.dir, ticker, name)})
+}
+
+// serveFirst writes the first existing file from candidates, or a 404.
+func (s *Server) serveFirst(w http.ResponseWriter, r *http.Request, candidates []string) {
+	for _, path := range candidates {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(content)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+func (s *Server) record(req Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+}
+
+// tickerFromRequest returns the companyCode parameter. The live portal pads the
+// code with a trailing space, so surrounding whitespace is dropped.
+func tickerFromRequest(r *http.Request) string {
+	return strings.ToUpper(strings.TrimSpace(r.FormValue("companyCode")))
+}
@@ -0,0 +1,119 @@
+// Package report summarizes a batch run: a JSON file for scripts and cron
+// jobs, and a short table for people reading the console.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+	"webscraper/internal/utils"
+)
+
+// Outcomes of a single ticker.
+const (
+	OutcomeOK       = "ok"
+	OutcomeFailed   = "failed"
+	OutcomeTimedOut = "timed_out"
+)
+
+// Ticker is the outcome of processing one ticker.
+type Ticker struct {
+	Ticker          string  `json:"ticker"`
+	Sector          string  `json:"sector,omitempty"`
+	Name            string  `json:"name,omitempty"`
+	Outcome         string  `json:"outcome"`
+	RowsFetched     int     `json:"rows_fetched"`
+	RowsAdded       int     `json:"rows_added"`
+	RowsRevised     int     `json:"rows_revised"`
+	Pages           int     `json:"pages"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	ErrorClass      string  `json:"error_class,omitempty"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// Report summarizes a batch run.
+type Report struct {
+	StartedAt       time.Time `json:"started_at"`
+	FinishedAt      time.Time `json:"finished_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Total           int       `json:"total"`
+	Succeeded       int       `json:"succeeded"`
+	Failed          int       `json:"failed"` // includes timed out tickers
+	TimedOut        int       `json:"timed_out"`
+	Tickers         []Ticker  `json:"tickers"`
+}
+
+// New creates a report for a run that started at startedAt, with tickers in
+// the order they were given.
+func New(startedAt time.Time, tickers []Ticker) *Report {
+	finishedAt := time.Now()
+	r := &Report{
+		StartedAt:       startedAt,
+		FinishedAt:      finishedAt,
+		DurationSeconds: Seconds(finishedAt.Sub(startedAt)),
+		Total:           len(tickers),
+		Tickers:         tickers,
+	}
+	for _, t := range tickers {
+		switch t.Outcome {
+		case OutcomeOK:
+			r.Succeeded++
+		case OutcomeTimedOut:
+			r.TimedOut++
+			r.Failed++
+		default:
+			r.Failed++
+		}
+	}
+	return r
+}
+
+// Seconds converts a duration to the seconds recorded in a report.
+func Seconds(d time.Duration) float64 {
+	return d.Round(time.Millisecond).Seconds()
+}
+
+// Exceeds reports whether more tickers failed than maxFailures allows. A
+// negative maxFailures never fails the run.
+func (r *Report) Exceeds(maxFailures int) bool {
+	return maxFailures >= 0 && r.Failed > maxFailures
+}
+
+// WriteJSON saves the report to path.
+func (r *Report) WriteJSON(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %v", err)
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(path, 0, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// WriteTable prints one line per ticker followed by the totals.
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TICKER\tSECTOR\tOUTCOME\tPAGES\tFETCHED\tADDED\tREVISED\tDURATION\tERROR")
+	for _, t := range r.Tickers {
+		sector := t.Sector
+		if sector == "" {
+			sector = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%.1fs\t%s\n",
+			t.Ticker, sector, t.Outcome, t.Pages, t.RowsFetched, t.RowsAdded, t.RowsRevised, t.DurationSeconds, t.ErrorClass)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d tickers: %d succeeded, %d failed (%d timed out) in %.0fs\n",
+		r.Total, r.Succeeded, r.Failed, r.TimedOut, r.DurationSeconds)
+	return err
+}
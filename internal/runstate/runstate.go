@@ -0,0 +1,159 @@
+// Package runstate records the progress of a batch run in a JSON file, so a
+// batch interrupted by a crash or a portal outage can be continued with
+// -resume or retried with -only-failed instead of starting over.
+package runstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"webscraper/internal/utils"
+)
+
+// Status is the progress of a single ticker within a run.
+type Status string
+
+const (
+	Pending Status = "pending"
+	Done    Status = "done"
+	Failed  Status = "failed"
+)
+
+// TickerState is the recorded progress of one ticker.
+type TickerState struct {
+	Status    Status    `json:"status"`
+	LastPage  int       `json:"last_page"` // last history page scraped
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// State is the progress of a batch run. It is saved to its file after every
+// update and is safe for use by concurrent workers.
+type State struct {
+	mu   sync.Mutex
+	path string
+
+	StartedAt time.Time               `json:"started_at"`
+	Tickers   map[string]*TickerState `json:"tickers"`
+}
+
+// New starts a fresh run over tickers, all pending, saved to path.
+func New(path string, tickers []string) *State {
+	state := &State{
+		path:      path,
+		StartedAt: time.Now(),
+		Tickers:   make(map[string]*TickerState, len(tickers)),
+	}
+	for _, ticker := range tickers {
+		state.Tickers[ticker] = &TickerState{Status: Pending, UpdatedAt: state.StartedAt}
+	}
+	return state
+}
+
+// Load reads the state saved at path. It returns nil and no error when no
+// state has been saved yet.
+func Load(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &State{path: path}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if state.Tickers == nil {
+		state.Tickers = make(map[string]*TickerState)
+	}
+	return state, nil
+}
+
+// Select returns the tickers of the given list that still need processing, in
+// list order: with onlyFailed those recorded as failed, otherwise those not
+// recorded as done. The selected tickers are marked pending.
+func (s *State) Select(tickers []string, onlyFailed bool) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var selected []string
+	for _, ticker := range tickers {
+		ts, ok := s.Tickers[ticker]
+		if onlyFailed {
+			if !ok || ts.Status != Failed {
+				continue
+			}
+		} else if ok && ts.Status == Done {
+			continue
+		}
+		selected = append(selected, ticker)
+	}
+
+	now := time.Now()
+	for _, ticker := range selected {
+		ts, ok := s.Tickers[ticker]
+		if !ok {
+			ts = &TickerState{}
+			s.Tickers[ticker] = ts
+		}
+		ts.Status = Pending
+		ts.UpdatedAt = now
+	}
+	return selected
+}
+
+// Count returns the number of tickers with the given status.
+func (s *State) Count(status Status) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, ts := range s.Tickers {
+		if ts.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Update records the outcome of ticker and saves the state. A nil err marks
+// the ticker done.
+func (s *State) Update(ticker string, lastPage int, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ts := &TickerState{Status: Done, LastPage: lastPage, UpdatedAt: time.Now()}
+	if err != nil {
+		ts.Status = Failed
+		ts.Error = err.Error()
+	}
+	s.Tickers[ticker] = ts
+	return s.save()
+}
+
+// Save writes the state to its file.
+func (s *State) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save()
+}
+
+func (s *State) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %v", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(s.path, 0, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
@@ -0,0 +1,108 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"webscraper/internal/utils"
+
+	// Embed the time zone database so Asia/Baghdad resolves on hosts and
+	// containers without one, such as Windows
+	_ "time/tzdata"
+)
+
+// DefaultTimezone is the exchange's time zone.
+const DefaultTimezone = "Asia/Baghdad"
+
+// Calendar knows the days the exchange trades: every day except the weekend
+// days and the listed holidays.
+type Calendar struct {
+	weekend  map[time.Weekday]bool
+	holidays map[string]bool
+}
+
+// NewCalendar creates a calendar from weekday names and holiday dates in
+// YYYY-MM-DD form.
+func NewCalendar(weekend, holidays []string) (*Calendar, error) {
+	c := &Calendar{
+		weekend:  make(map[time.Weekday]bool),
+		holidays: make(map[string]bool),
+	}
+	for _, name := range weekend {
+		day, err := parseWeekday(name)
+		if err != nil {
+			return nil, err
+		}
+		c.weekend[day] = true
+	}
+	for _, date := range holidays {
+		parsed, err := time.Parse(utils.DateLayout, strings.TrimSpace(date))
+		if err != nil {
+			return nil, fmt.Errorf("invalid holiday %q (want YYYY-MM-DD): %v", date, err)
+		}
+		c.holidays[parsed.Format(utils.DateLayout)] = true
+	}
+	return c, nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	name = strings.TrimSpace(name)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := day.String()
+		if strings.EqualFold(name, full) || strings.EqualFold(name, full[:3]) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", name)
+}
+
+// IsTradingDay reports whether the exchange trades on the date of t, taken in
+// t's location.
+func (c *Calendar) IsTradingDay(t time.Time) bool {
+	if c.weekend[t.Weekday()] {
+		return false
+	}
+	return !c.holidays[t.Format(utils.DateLayout)]
+}
+
+// Schedule combines a cron schedule with the trading calendar: scheduled
+// times falling on days the exchange does not trade are skipped.
+type Schedule struct {
+	Cron     *Cron
+	Calendar *Calendar
+	Location *time.Location
+}
+
+// New creates a schedule from a cron spec evaluated in the named time zone.
+func New(spec, timezone string, calendar *Calendar) (*Schedule, error) {
+	cron, err := ParseCron(spec)
+	if err != nil {
+		return nil, err
+	}
+	if timezone == "" {
+		timezone = DefaultTimezone
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %v", timezone, err)
+	}
+	return &Schedule{Cron: cron, Calendar: calendar, Location: loc}, nil
+}
+
+// Next returns the first scheduled time after t on a trading day, in the
+// schedule's time zone, or the zero time if there is none within five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	next := t.In(s.Location)
+	limit := next.AddDate(5, 0, 0)
+	for {
+		next = s.Cron.Next(next)
+		if next.IsZero() || next.After(limit) {
+			return time.Time{}
+		}
+		if s.Calendar == nil || s.Calendar.IsTradingDay(next) {
+			return next
+		}
+		// Skip the rest of the non-trading day
+		next = time.Date(next.Year(), next.Month(), next.Day(), 23, 59, 0, 0, s.Location)
+	}
+}
@@ -0,0 +1,74 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	calendar, err := NewCalendar([]string{"Friday", "sat"}, []string{"2025-01-06"})
+	if err != nil {
+		t.Fatalf("NewCalendar failed: %v", err)
+	}
+	s, err := New("30 13 * * *", "Asia/Baghdad", calendar)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	baghdad, err := time.LoadLocation("Asia/Baghdad")
+	if err != nil {
+		t.Fatalf("failed to load Asia/Baghdad: %v", err)
+	}
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2025, month, day, hour, minute, 0, 0, baghdad)
+	}
+
+	tests := []struct {
+		name string
+		from time.Time
+		want time.Time
+	}{
+		{"later the same trading day", at(1, 2, 9, 0), at(1, 2, 13, 30)},
+		// 10:00 UTC is 13:00 in Baghdad, before the run
+		{"from UTC", time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC), at(1, 2, 13, 30)},
+		// Thursday after the run: Friday and Saturday are skipped
+		{"over the weekend", at(1, 2, 14, 0), at(1, 5, 13, 30)},
+		{"from the weekend", at(1, 3, 8, 0), at(1, 5, 13, 30)},
+		// Sunday after the run: Monday 6 January is a holiday
+		{"over a holiday", at(1, 5, 14, 0), at(1, 7, 13, 30)},
+	}
+	for _, tt := range tests {
+		got := s.Next(tt.from)
+		if !got.Equal(tt.want) {
+			t.Errorf("%s: Next(%s) = %s, want %s", tt.name, tt.from, got, tt.want)
+		}
+		if got.Location().String() != "Asia/Baghdad" {
+			t.Errorf("%s: Next returned a time in %s, want Asia/Baghdad", tt.name, got.Location())
+		}
+	}
+}
+
+func TestScheduleNextNoTradingDays(t *testing.T) {
+	calendar, err := NewCalendar([]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}, nil)
+	if err != nil {
+		t.Fatalf("NewCalendar failed: %v", err)
+	}
+	s, err := New("30 13 * * *", "", calendar)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if got := s.Next(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("Next = %s, want the zero time", got)
+	}
+}
+
+func TestNewCalendarInvalid(t *testing.T) {
+	if _, err := NewCalendar([]string{"Fryday"}, nil); err == nil {
+		t.Error("NewCalendar accepted an invalid weekday")
+	}
+	if _, err := NewCalendar(nil, []string{"06/01/2025"}); err == nil {
+		t.Error("NewCalendar accepted a holiday not in YYYY-MM-DD form")
+	}
+	if _, err := New("30 13 * * *", "Mars/Olympus", nil); err == nil {
+		t.Error("New accepted an unknown time zone")
+	}
+}
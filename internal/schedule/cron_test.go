@@ -0,0 +1,145 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+// bits returns the field bit set holding values.
+func bits(values ...int) uint64 {
+	var b uint64
+	for _, v := range values {
+		b |= 1 << uint(v)
+	}
+	return b
+}
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		spec   string
+		minute uint64
+		hour   uint64
+		dom    uint64
+		month  uint64
+		dow    uint64
+	}{
+		{"30 13 * * *", bits(30), bits(13), bits(rangeOf(1, 31)...), bits(rangeOf(1, 12)...), bits(rangeOf(0, 7)...)},
+		{"0-4 1,3,5 1-3 * *", bits(0, 1, 2, 3, 4), bits(1, 3, 5), bits(1, 2, 3), bits(rangeOf(1, 12)...), bits(rangeOf(0, 7)...)},
+		{"*/20 */6 * * *", bits(0, 20, 40), bits(0, 6, 12, 18), bits(rangeOf(1, 31)...), bits(rangeOf(1, 12)...), bits(rangeOf(0, 7)...)},
+		{"5/15 10-20/5 * * *", bits(5, 20, 35, 50), bits(10, 15, 20), bits(rangeOf(1, 31)...), bits(rangeOf(1, 12)...), bits(rangeOf(0, 7)...)},
+		{"0 0 * jan-mar,DEC *", bits(0), bits(0), bits(rangeOf(1, 31)...), bits(1, 2, 3, 12), bits(rangeOf(0, 7)...)},
+		{"0 0 * * sun-thu", bits(0), bits(0), bits(rangeOf(1, 31)...), bits(rangeOf(1, 12)...), bits(0, 1, 2, 3, 4)},
+		{"0 0 * * Mon-Fri/2", bits(0), bits(0), bits(rangeOf(1, 31)...), bits(rangeOf(1, 12)...), bits(1, 3, 5)},
+		{"0 0 * * 7", bits(0), bits(0), bits(rangeOf(1, 31)...), bits(rangeOf(1, 12)...), bits(0, 7)},
+		{"0 0 * * 5-7", bits(0), bits(0), bits(rangeOf(1, 31)...), bits(rangeOf(1, 12)...), bits(0, 5, 6, 7)},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.spec)
+		if err != nil {
+			t.Errorf("ParseCron(%q) failed: %v", tt.spec, err)
+			continue
+		}
+		if c.minute != tt.minute || c.hour != tt.hour || c.dom != tt.dom || c.month != tt.month || c.dow != tt.dow {
+			t.Errorf("ParseCron(%q) = minute %b hour %b dom %b month %b dow %b, want %b %b %b %b %b",
+				tt.spec, c.minute, c.hour, c.dom, c.month, c.dow, tt.minute, tt.hour, tt.dom, tt.month, tt.dow)
+		}
+		if c.String() != tt.spec {
+			t.Errorf("ParseCron(%q).String() = %q", tt.spec, c.String())
+		}
+	}
+}
+
+func TestParseCronDayFields(t *testing.T) {
+	tests := []struct {
+		spec   string
+		anyDOM bool
+		anyDOW bool
+	}{
+		{"0 0 * * *", true, true},
+		{"0 0 */1 * sun-thu", true, false},
+		{"0 0 1 * */2", false, true},
+		{"0 0 1-31 * mon", false, false},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) failed: %v", tt.spec, err)
+		}
+		if c.anyDOM != tt.anyDOM || c.anyDOW != tt.anyDOW {
+			t.Errorf("ParseCron(%q) any day of month/week = %v/%v, want %v/%v", tt.spec, c.anyDOM, c.anyDOW, tt.anyDOM, tt.anyDOW)
+		}
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"-1 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 0 *",
+		"* * * 13 *",
+		"* * * * 8",
+		"* * * foo *",
+		"* * * * sunday",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"1,,2 * * * *",
+		"* * * * mon-",
+	}
+	for _, spec := range specs {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"30 13 * * *", at(2025, 1, 1, 12, 0), at(2025, 1, 1, 13, 30)},
+		{"30 13 * * *", at(2025, 1, 1, 13, 30), at(2025, 1, 2, 13, 30)},
+		{"30 13 * * *", time.Date(2025, 1, 1, 13, 29, 59, 0, time.UTC), at(2025, 1, 1, 13, 30)},
+		{"*/15 * * * *", at(2025, 1, 1, 10, 7), at(2025, 1, 1, 10, 15)},
+		{"0 0 1 * *", at(2025, 1, 31, 10, 0), at(2025, 2, 1, 0, 0)},
+		{"0 0 31 * *", at(2025, 1, 31, 10, 0), at(2025, 3, 31, 0, 0)},
+		{"0 0 1 1 *", at(2025, 6, 1, 0, 0), at(2026, 1, 1, 0, 0)},
+		{"59 23 31 12 *", at(2025, 12, 31, 23, 59), at(2026, 12, 31, 23, 59)},
+		{"0 0 29 2 *", at(2025, 1, 1, 0, 0), at(2028, 2, 29, 0, 0)},
+		{"0 0 30 2 *", at(2025, 1, 1, 0, 0), time.Time{}},
+		// 2025-01-02 is a Thursday; */1 leaves the weekdays in charge
+		{"0 13 */1 * sun-thu", at(2025, 1, 2, 13, 0), at(2025, 1, 5, 13, 0)},
+		{"0 13 * * 7", at(2025, 1, 2, 13, 0), at(2025, 1, 5, 13, 0)},
+		// Both day fields restricted: either may match
+		{"0 0 13 * fri", at(2025, 1, 1, 0, 0), at(2025, 1, 3, 0, 0)},
+		{"0 0 2 * fri", at(2025, 1, 1, 0, 0), at(2025, 1, 2, 0, 0)},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) failed: %v", tt.spec, err)
+		}
+		if got := c.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("ParseCron(%q).Next(%s) = %s, want %s", tt.spec, tt.from, got, tt.want)
+		}
+	}
+}
+
+func rangeOf(lo, hi int) []int {
+	values := make([]int, 0, hi-lo+1)
+	for v := lo; v <= hi; v++ {
+		values = append(values, v)
+	}
+	return values
+}
@@ -0,0 +1,112 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Cancellation causes reported when a deadline cuts a scrape short.
+var (
+	// ErrTickerTimeout means a single ticker exceeded scraper.timeout.
+	ErrTickerTimeout = errors.New("ticker timed out")
+	// ErrRunTimeout means the whole run exceeded its deadline.
+	ErrRunTimeout = errors.New("run timed out")
+)
+
+// IsTimeout reports whether err was caused by a ticker or run deadline.
+func IsTimeout(err error) bool {
+	return errors.Is(err, ErrTickerTimeout) || errors.Is(err, ErrRunTimeout)
+}
+
+// tickerTimeout returns the per-ticker deadline configured in scraper.timeout.
+func (s *Scraper) tickerTimeout() time.Duration {
+	return time.Duration(s.config.Scraper.Timeout) * time.Second
+}
+
+// run executes actions in the current tab, bounded by the deadline of the
+// operation in progress.
+func (s *Scraper) run(actions ...chromedp.Action) error {
+	return s.runTimeout(0, actions...)
+}
+
+// runTimeout is like run but additionally gives up after timeout when it is
+// positive. The actions run in a context derived from the current tab, so the
+// deadline keeps applying after the tab is replaced by RefreshTab.
+func (s *Scraper) runTimeout(timeout time.Duration, actions ...chromedp.Action) error {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if s.op != nil {
+		stop := context.AfterFunc(s.op, cancel)
+		defer stop()
+	}
+
+	err := chromedp.Run(ctx, actions...)
+	if err != nil {
+		if opErr := s.opErr(); opErr != nil {
+			return opErr
+		}
+	}
+	return err
+}
+
+// sleep waits for d, returning early when ctx is done, the operation in
+// progress hits its deadline or the tab is closed.
+func (s *Scraper) sleep(ctx context.Context, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	var opDone <-chan struct{}
+	if s.op != nil {
+		opDone = s.op.Done()
+	}
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	case <-opDone:
+	case <-s.ctx.Done():
+	}
+}
+
+// waitForSlot blocks until the rate limiter admits another portal request.
+func (s *Scraper) waitForSlot() error {
+	ctx := s.ctx
+	if s.op != nil {
+		ctx = s.op
+	}
+	if err := s.limiter.Wait(ctx); err != nil {
+		if opErr := s.opErr(); opErr != nil {
+			return opErr
+		}
+		return err
+	}
+	return nil
+}
+
+// opContext returns the context of the operation in progress, or a context
+// that is never done when there is none.
+func (s *Scraper) opContext() context.Context {
+	if s.op == nil {
+		return context.Background()
+	}
+	return s.op
+}
+
+// opErr returns the cancellation cause once the current operation's deadline
+// has passed, and nil otherwise.
+func (s *Scraper) opErr() error {
+	if s.op == nil || s.op.Err() == nil {
+		return nil
+	}
+	return context.Cause(s.op)
+}
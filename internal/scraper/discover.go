@@ -0,0 +1,97 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"webscraper/models"
+
+	"github.com/chromedp/chromedp"
+)
+
+// CompaniesPage is the portal page listing the companies on the exchange,
+// grouped by sector.
+const CompaniesPage = "listedCompanies.html"
+
+// ErrNoCompanies is returned when no listed company could be read from the
+// portal.
+var ErrNoCompanies = errors.New("no listed companies found on the portal")
+
+// discoveryPages are the portal pages read to discover listed companies: the
+// listed-companies table, and a profile page for the ticker strip shown
+// above it.
+var discoveryPages = []string{
+	CompaniesPage + "?currLanguage=en",
+	"companyprofilecontainer.html?currLanguage=en&activeTab=0",
+}
+
+// DiscoverCompanies reads the companies listed on the exchange from the
+// portal's listed-companies page and ticker strip, sorted by symbol. A page
+// that fails to load is logged and skipped; ErrNoCompanies is returned if no
+// company is found on any of them. The discovery stops when ctx is done or
+// after the per-ticker timeout.
+func (s *Scraper) DiscoverCompanies(ctx context.Context) ([]models.Company, error) {
+	op, cancel := context.WithTimeoutCause(ctx, s.tickerTimeout(), ErrTickerTimeout)
+	defer cancel()
+	s.op = op
+	defer func() { s.op = nil }()
+
+	var links []models.RawLink
+	var tables []models.RawTable
+	for _, path := range discoveryPages {
+		url := fmt.Sprintf("%s/%s", s.baseURL(), path)
+		pageLinks, pageTables, err := s.readCompanyPage(url)
+		if err != nil {
+			if opErr := s.opErr(); opErr != nil {
+				return nil, opErr
+			}
+			s.logger.Warn("Failed to read %s: %v", url, err)
+			continue
+		}
+		s.logger.Debug("Read %d links and %d tables from %s", len(pageLinks), len(pageTables), url)
+		links = append(links, pageLinks...)
+		tables = append(tables, pageTables...)
+	}
+
+	companies := models.ParseCompanies(links, tables)
+	if len(companies) == 0 {
+		return nil, ErrNoCompanies
+	}
+	s.logger.Info("Discovered %d listed companies", len(companies))
+	return companies, nil
+}
+
+// readCompanyPage loads url in the current tab and returns the profile links
+// and the tables on it.
+func (s *Scraper) readCompanyPage(url string) ([]models.RawLink, []models.RawTable, error) {
+	if err := s.waitForSlot(); err != nil {
+		return nil, nil, err
+	}
+
+	var result struct {
+		Links  []models.RawLink
+		Tables []models.RawTable
+	}
+	err := s.run(
+		chromedp.Navigate(url),
+		chromedp.WaitReady("body"),
+		chromedp.Evaluate(`
+			(() => {
+				const text = el => el.textContent.replace(/\s+/g, ' ').trim();
+				return {
+					Links: Array.from(document.querySelectorAll('a[href*="companyCode="]')).map(a => ({
+						Href: a.href,
+						Text: text(a)
+					})),
+					Tables: Array.from(document.querySelectorAll('table')).map(table => ({
+						Rows: Array.from(table.rows).map(row => Array.from(row.cells).map(text))
+					}))
+				};
+			})()
+		`, &result),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result.Links, result.Tables, nil
+}
@@ -0,0 +1,178 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+	"webscraper/models"
+)
+
+// ErrTableMissing is returned when the history table is not on the page,
+// usually because an AJAX refresh has not completed yet.
+var ErrTableMissing = errors.New("results table dispTable not found")
+
+// ErrSave wraps errors from saving or exporting scraped history.
+var ErrSave = errors.New("failed to save data")
+
+// Backoff bounds used between retry attempts.
+const (
+	retryBaseDelay = 1 * time.Second
+	retryMaxDelay  = 30 * time.Second
+)
+
+// fatalError marks an error that retrying cannot fix.
+type fatalError struct {
+	err error
+}
+
+func (e *fatalError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fatalError) Unwrap() error {
+	return e.err
+}
+
+// fatal marks err as not worth retrying.
+func fatal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &fatalError{err: err}
+}
+
+// IsRetryable reports whether err is a transient failure worth retrying:
+// timeouts, canceled tab contexts, network errors and a missing or stale
+// results table.
+// Errors marked fatal and anything unrecognized are not retried.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var fatalErr *fatalError
+	if errors.As(err, &fatalErr) {
+		return false
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, ErrTableMissing) ||
+		errors.Is(err, ErrPageNotChanged) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	// chromedp reports page-level network failures as plain messages
+	msg := err.Error()
+	return strings.Contains(msg, "net::ERR_") || strings.Contains(msg, "timeout")
+}
+
+// retryDelay returns the backoff before the given retry (1-based): the delay
+// doubles with each attempt up to retryMaxDelay, and a random half of it is
+// jittered so concurrent workers do not retry in lockstep.
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay << (attempt - 1)
+	if delay <= 0 || delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// withRetry runs fn until it succeeds, fails with a non-retryable error or the
+// configured number of retries is used up. fn receives the 1-based attempt
+// number so later attempts can restore page state before repeating the step.
+// If the tab itself was lost, it is replaced before the next attempt.
+func (s *Scraper) withRetry(step string, fn func(attempt int) error) error {
+	retries := s.config.Scraper.Retries
+	if retries < 0 {
+		retries = 0
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn(attempt)
+		if err == nil {
+			return nil
+		}
+		if opErr := s.opErr(); opErr != nil {
+			// The ticker or run deadline passed; further attempts cannot succeed
+			return fmt.Errorf("%s: %w", step, opErr)
+		}
+		if !IsRetryable(err) {
+			return err
+		}
+		if attempt > retries {
+			return fmt.Errorf("%s failed after %d attempts: %w", step, attempt, err)
+		}
+
+		delay := retryDelay(attempt)
+		s.metrics.Retried(s.ticker)
+		s.logger.With("step", step).Warn("%s failed (attempt %d/%d): %v; retrying in %v",
+			step, attempt, retries+1, err, delay.Round(time.Millisecond))
+
+		var opDone <-chan struct{}
+		if s.op != nil {
+			opDone = s.op.Done()
+		}
+		select {
+		case <-time.After(delay):
+		case <-s.browserCtx.Done():
+			return fmt.Errorf("%s: browser closed while waiting to retry: %w", step, err)
+		case <-opDone:
+			return fmt.Errorf("%s: %w", step, s.opErr())
+		}
+
+		if s.ctx.Err() != nil {
+			if refreshErr := s.RefreshTabContext(s.opContext()); refreshErr != nil {
+				return fmt.Errorf("%s: %v (tab refresh failed: %w)", step, err, refreshErr)
+			}
+		}
+	}
+}
+
+// Error classes returned by ErrorClass.
+const (
+	ClassTimeout  = "timeout"
+	ClassNetwork  = "network"
+	ClassPageLoad = "page_load"
+	ClassRejected = "rejected"
+	ClassParse    = "parse"
+	ClassStorage  = "storage"
+	ClassOther    = "other"
+)
+
+// ErrorClass sorts an error returned by GetStockData or Save into a coarse
+// class for run reports. It returns "" for a nil error.
+func ErrorClass(err error) string {
+	var (
+		fatalErr *fatalError
+		parseErr models.ParseErrors
+		netErr   net.Error
+	)
+	switch {
+	case err == nil:
+		return ""
+	case IsTimeout(err) || errors.Is(err, context.DeadlineExceeded):
+		return ClassTimeout
+	case errors.Is(err, ErrSave):
+		return ClassStorage
+	case errors.As(err, &parseErr):
+		return ClassParse
+	case errors.As(err, &fatalErr):
+		return ClassRejected
+	case errors.Is(err, ErrTableMissing) || errors.Is(err, ErrPageNotChanged):
+		return ClassPageLoad
+	case errors.As(err, &netErr) || strings.Contains(err.Error(), "net::ERR_"):
+		return ClassNetwork
+	}
+	return ClassOther
+}
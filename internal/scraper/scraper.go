@@ -1,561 +1,927 @@
-package scraper
-
-import (
-	"context"
-	"encoding/csv"
-	"fmt"
-	"os"
-	"strconv"
-	"time"
-	"webscraper/internal/utils"
-
-	"github.com/chromedp/cdproto/emulation"
-	"github.com/chromedp/cdproto/network"
-	"github.com/chromedp/cdproto/page"
-	"github.com/chromedp/chromedp"
-)
-
-// StockData represents the structure of our scraped data
-type StockData struct {
-	Date        string
-	OpenPrice   string
-	HighPrice   string
-	LowPrice    string
-	ClosePrice  string
-	Volume      string
-	TotalShares string
-	NumTrades   string
-	Change      float64
-	ChangePerc  float64
-}
-
-type Scraper struct {
-	logger      *utils.Logger
-	ctx         context.Context
-	cancel      context.CancelFunc
-	config      *utils.Config
-	perfTracker *utils.PerformanceTracker
-}
-
-func NewScraper(logger *utils.Logger, ctx context.Context, cancel context.CancelFunc, config *utils.Config) *Scraper {
-	return &Scraper{
-		logger:      logger,
-		ctx:         ctx,
-		cancel:      cancel,
-		config:      config,
-		perfTracker: utils.NewPerformanceTracker(),
-	}
-}
-
-func (s *Scraper) GetStockData(ticker string) ([]StockData, error) {
-	// Try to load existing data
-	existingData, err := s.loadExistingData(ticker)
-	if err != nil {
-		s.logger.Debug("Error loading existing data: %v", err)
-		// Continue with full scrape if there's an error
-	}
-
-	// Disable image loading before navigation
-	err = chromedp.Run(s.ctx,
-		network.Enable(),
-		emulation.SetCPUThrottlingRate(1),
-		network.SetExtraHTTPHeaders(map[string]interface{}{
-			"Accept": "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
-		}),
-		network.SetBlockedURLS([]string{
-			"*.png",
-			"*.jpg",
-			"*.jpeg",
-			"*.gif",
-			"*.webp",
-			"*.svg",
-			"*.ico",
-		}),
-	)
-	if err != nil {
-		s.logger.Debug("Failed to set image blocking: %v", err)
-		// Continue anyway as this is not critical
-	}
-
-	url := fmt.Sprintf("http://www.isx-iq.net/isxportal/portal/companyprofilecontainer.html?currLanguage=en&companyCode=%s%%20&activeTab=0", ticker)
-	fmt.Printf("Starting data extraction for ticker: %s\n", ticker)
-
-	// Add dialog handler before navigation
-	chromedp.ListenTarget(s.ctx, func(ev interface{}) {
-		if ev, ok := ev.(*page.EventJavascriptDialogOpening); ok {
-			s.logger.Debug("Dialog detected: %s", ev.Message)
-			go func() {
-				if err := chromedp.Run(s.ctx,
-					page.HandleJavaScriptDialog(true),
-				); err != nil {
-					s.logger.Debug("Failed to handle dialog: %v", err)
-				}
-			}()
-		}
-	})
-
-	// Navigate to the page
-	err = chromedp.Run(s.ctx,
-		chromedp.Navigate(url),
-		chromedp.WaitReady("body"),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to navigate: %v", err)
-	}
-
-	// Set up date range and trigger search
-	err = chromedp.Run(s.ctx,
-		chromedp.Evaluate(`
-			(() => {
-				const dateInput = document.querySelector("#fromDate");
-				dateInput.value = "01/01/2020";
-				const event = new Event('change', { bubbles: true });
-				dateInput.dispatchEvent(event);
-
-				const searchButton = document.querySelector("#command > div.filterbox > div.button-all > input[type=button]");
-				searchButton.click();
-				return true;
-			})()
-		`, nil),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to set date range: %v", err)
-	}
-
-	// Wait for table to load
-	time.Sleep(2 * time.Second)
-
-	var allStockData []StockData
-	currentPage := 1
-	maxPages := s.config.Scraper.MaxPages
-	foundOverlap := false
-	previousPageCount := 0 // Track previous page record count
-
-	fmt.Printf("Starting data extraction, will process %d pages\n", maxPages)
-
-	for currentPage <= maxPages && !foundOverlap {
-		// Extract data from current page
-		var pageData []StockData
-		err = chromedp.Run(s.ctx,
-			chromedp.Evaluate(`
-				(() => {
-					const table = document.getElementById('dispTable');
-					const rows = table.querySelectorAll('tbody tr');
-					return Array.from(rows).map(row => {
-						const cells = row.querySelectorAll('td');
-						return {
-							Date: cells[9].textContent.trim(),
-							OpenPrice: cells[7].textContent.trim(),
-							HighPrice: cells[6].textContent.trim(),
-							LowPrice: cells[5].textContent.trim(),
-							ClosePrice: cells[8].textContent.trim(),
-							Volume: cells[1].textContent.trim(),
-							TotalShares: cells[2].textContent.trim(),
-							NumTrades: cells[0].textContent.trim()
-						};
-					});
-				})()
-			`, &pageData),
-		)
-		if err != nil {
-			fmt.Printf("Error extracting data from page %d: %v\n", currentPage, err)
-			return nil, fmt.Errorf("failed to extract data from page %d: %v", currentPage, err)
-		}
-
-		// Check if we've reached the end of data
-		if len(pageData) == 0 {
-			s.logger.Debug("No more data found on page %d, stopping extraction", currentPage)
-			break
-		}
-
-		// Check if we got fewer records than the previous page (last page usually has fewer records)
-		if previousPageCount > 0 && len(pageData) < previousPageCount {
-			s.logger.Debug("Found last page with %d records (previous had %d)", len(pageData), previousPageCount)
-		}
-		previousPageCount = len(pageData)
-
-		fmt.Printf("Successfully extracted %d records from page %d\n", len(pageData), currentPage)
-
-		if len(existingData) > 0 {
-			foundOverlap = s.findOverlap(existingData, pageData)
-			if foundOverlap {
-				s.logger.Debug("Found overlap with existing data on page %d", currentPage)
-				// Only keep new data (before overlap)
-				for i, record := range pageData {
-					if record.Date == existingData[0].Date {
-						pageData = pageData[:i]
-						break
-					}
-				}
-			}
-		}
-
-		allStockData = append(allStockData, pageData...)
-
-		if foundOverlap {
-			s.logger.Info("Found overlap with existing data, stopping extraction")
-			break
-		}
-
-		// Check if we've reached the end of data
-		if len(pageData) < 25 { // Assuming 25 is the standard page size
-			s.logger.Debug("Reached last page (incomplete page), stopping extraction")
-			break
-		}
-
-		if currentPage >= maxPages {
-			break
-		}
-
-		// Navigate to next page
-		nextPage := currentPage + 1
-		fmt.Printf("Navigating to page %d...\n", nextPage)
-		err = chromedp.Run(s.ctx,
-			chromedp.Evaluate(fmt.Sprintf(`
-				(() => {
-					doAjax('companyperformancehistoryfilter.html',
-						'fromDate=01/01/2020&d-6716032-p=%d&toDate=23/12/2024&companyCode=%s',
-						'ajxDspId');
-					return true;
-				})()
-			`, nextPage, ticker), nil),
-		)
-		if err != nil {
-			fmt.Printf("Failed to navigate to page %d: %v\n", nextPage, err)
-			break
-		}
-
-		time.Sleep(time.Duration(s.config.Scraper.Delay) * time.Second)
-		currentPage++
-	}
-
-	// Append existing data if we have any
-	if len(existingData) > 0 {
-		allStockData = append(allStockData, existingData...)
-	}
-
-	// Calculate changes for all data
-	allStockData = s.calculatePriceChanges(allStockData)
-
-	return allStockData, nil
-}
-
-func (s *Scraper) SaveToCSV(ticker string, data []StockData) error {
-	if len(data) == 0 {
-		return fmt.Errorf("no data to save")
-	}
-
-	// Create the output directory if it doesn't exist
-	err := os.MkdirAll("output", 0755)
-	if err != nil {
-		return fmt.Errorf("failed to create output directory: %v", err)
-	}
-
-	// Create CSV file
-	filename := fmt.Sprintf("output/%s_data.csv", ticker)
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("failed to create CSV file: %v", err)
-	}
-	defer file.Close()
-
-	// Create CSV writer
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// Write header with new column
-	headers := []string{"Date", "Open", "High", "Low", "Close", "Change", "Change%", "Volume", "T.Shares", "Trades"}
-	if err := writer.Write(headers); err != nil {
-		return fmt.Errorf("failed to write headers: %v", err)
-	}
-
-	// Write data including new fields
-	for _, record := range data {
-		row := []string{
-			record.Date,
-			record.OpenPrice,
-			record.HighPrice,
-			record.LowPrice,
-			record.ClosePrice,
-			fmt.Sprintf("%.3f", record.Change),       // Change
-			fmt.Sprintf("%.2f%%", record.ChangePerc), // Change%
-			record.Volume,
-			record.TotalShares,
-			record.NumTrades,
-		}
-		if err := writer.Write(row); err != nil {
-			return fmt.Errorf("failed to write record: %v", err)
-		}
-	}
-
-	s.logger.Info("Successfully saved data to %s", filename)
-	return nil
-}
-
-func (s *Scraper) Close() {
-	fmt.Println("\nClosing browser...")
-	if s.cancel != nil {
-		// Create a new context with a short timeout for cleanup
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		// Try to close gracefully
-		if err := chromedp.Run(ctx, chromedp.Stop()); err != nil {
-			fmt.Printf("Error during graceful shutdown: %v\n", err)
-		}
-
-		s.cancel()
-		time.Sleep(2 * time.Second)
-		fmt.Println("Browser closed successfully")
-	}
-}
-
-func (s *Scraper) GetPerformanceTracker() *utils.PerformanceTracker {
-	return s.perfTracker
-}
-
-// PreflightCheck verifies all dependencies and configurations
-func (s *Scraper) PreflightCheck() error {
-	checks := []struct {
-		name  string
-		check func() error
-	}{
-		{"Config Validation", s.validateConfig},
-		{"Directory Structure", s.checkDirectories},
-		{"Browser Launch", s.testBrowserLaunch},
-		{"Network Settings", s.testNetworkSettings},
-	}
-
-	for _, c := range checks {
-		s.logger.Debug("Running preflight check: %s", c.name)
-		if err := c.check(); err != nil {
-			return fmt.Errorf("%s check failed: %v", c.name, err)
-		}
-		s.logger.Debug("%s check passed", c.name)
-	}
-
-	return nil
-}
-
-func (s *Scraper) validateConfig() error {
-	if s.config == nil {
-		return fmt.Errorf("configuration is nil")
-	}
-	if s.config.Scraper.Timeout <= 0 {
-		return fmt.Errorf("invalid timeout value")
-	}
-	if s.config.Scraper.MaxPages <= 0 {
-		return fmt.Errorf("invalid max pages value")
-	}
-	return nil
-}
-
-func (s *Scraper) checkDirectories() error {
-	dirs := []string{
-		"output",
-		"logs",
-		"temp_builds",
-	}
-	for _, dir := range dirs {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("failed to create %s directory: %v", dir, err)
-		}
-	}
-	return nil
-}
-
-func (s *Scraper) testBrowserLaunch() error {
-	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Second)
-	defer cancel()
-
-	return chromedp.Run(ctx, chromedp.Navigate("about:blank"))
-}
-
-func (s *Scraper) testNetworkSettings() error {
-	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Second)
-	defer cancel()
-
-	return chromedp.Run(ctx,
-		network.Enable(),
-		network.SetCacheDisabled(true),
-		emulation.SetUserAgentOverride("Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/91.0.4472.124 Safari/537.36"),
-	)
-}
-
-// Add browser refresh mechanism
-func (s *Scraper) refreshBrowser() error {
-	s.logger.Debug("Refreshing browser session")
-
-	// Cancel old context
-	if s.cancel != nil {
-		s.cancel()
-	}
-
-	// Create new context and browser
-	ctx, cancel := chromedp.NewContext(context.Background())
-	s.ctx = ctx
-	s.cancel = cancel
-
-	// Test new browser
-	err := chromedp.Run(ctx, chromedp.Navigate("about:blank"))
-	if err != nil {
-		return fmt.Errorf("failed to refresh browser: %v", err)
-	}
-
-	return nil
-}
-
-// Add this function before processTickerList
-func processSingleTicker(s *Scraper, logger *utils.Logger, ticker string) error {
-	logger.Info("Processing ticker: %s", ticker)
-
-	// Get stock data
-	stockDataList, err := s.GetStockData(ticker)
-	if err != nil {
-		logger.Error("Error processing %s: %v", ticker, err)
-		return err
-	}
-
-	// Save the fetched data to a CSV file
-	err = s.SaveToCSV(ticker, stockDataList)
-	if err != nil {
-		logger.Error("Error saving data for %s: %v", ticker, err)
-		return err
-	}
-
-	logger.Info("Successfully processed %s. Data saved to output/%s_data.csv", ticker, ticker)
-	return nil
-}
-
-// Update processTickerList in main.go to handle browser refresh
-func processTickerList(s *Scraper, logger *utils.Logger, tickers []string) error {
-	totalTickers := len(tickers)
-	logger.Info("Starting to process %d tickers", totalTickers)
-
-	for i, ticker := range tickers {
-		logger.Info("Processing ticker %d/%d: %s", i+1, totalTickers, ticker)
-
-		// Refresh browser every 5 tickers
-		if i > 0 && i%5 == 0 {
-			logger.Debug("Performing browser refresh")
-			if err := s.refreshBrowser(); err != nil {
-				logger.Error("Failed to refresh browser: %v", err)
-				time.Sleep(30 * time.Second) // Hard-coded 30 second wait
-				continue
-			}
-		}
-
-		err := processSingleTicker(s, logger, ticker)
-		if err != nil {
-			logger.Error("Failed to process ticker %s: %v", ticker, err)
-			// If navigation fails, try refreshing the browser
-			if err.Error() == "failed to navigate: context canceled" {
-				logger.Debug("Navigation failed, refreshing browser")
-				if err := s.refreshBrowser(); err != nil {
-					logger.Error("Failed to refresh browser: %v", err)
-				}
-			}
-			time.Sleep(10 * time.Second)
-			continue
-		}
-
-		if i < totalTickers-1 {
-			logger.Debug("Waiting 10 seconds before next ticker")
-			time.Sleep(10 * time.Second)
-		}
-	}
-
-	// Generate and log aggregate performance report
-	report := s.GetPerformanceTracker().GenerateAggregateReport()
-	logger.Info("Aggregate Performance Report:\n%s", report)
-
-	logger.Info("Completed processing %d tickers", totalTickers)
-	return nil
-}
-
-// Add calculation function
-func (s *Scraper) calculatePriceChanges(data []StockData) []StockData {
-	if len(data) < 2 {
-		return data
-	}
-
-	// Process each day's data starting from most recent (data is in reverse chronological order)
-	for i := 0; i < len(data)-1; i++ {
-		currentClose, err := strconv.ParseFloat(data[i].ClosePrice, 64)
-		if err != nil {
-			s.logger.Debug("Error parsing current close price: %v", err)
-			continue
-		}
-
-		previousClose, err := strconv.ParseFloat(data[i+1].ClosePrice, 64)
-		if err != nil {
-			s.logger.Debug("Error parsing previous close price: %v", err)
-			continue
-		}
-
-		// Calculate change and change percentage
-		data[i].Change = currentClose - previousClose
-		if previousClose != 0 {
-			data[i].ChangePerc = (data[i].Change / previousClose) * 100
-		}
-	}
-
-	return data
-}
-
-// Add function to load existing data
-func (s *Scraper) loadExistingData(ticker string) ([]StockData, error) {
-	filename := fmt.Sprintf("output/%s_data.csv", ticker)
-	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		return nil, nil // File doesn't exist
-	}
-
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	// Skip header
-	if _, err := reader.Read(); err != nil {
-		return nil, err
-	}
-
-	var data []StockData
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, err
-	}
-
-	for _, record := range records {
-		data = append(data, StockData{
-			Date:        record[0],
-			OpenPrice:   record[1],
-			HighPrice:   record[2],
-			LowPrice:    record[3],
-			ClosePrice:  record[4],
-			Volume:      record[7],
-			TotalShares: record[8],
-			NumTrades:   record[9],
-		})
-	}
-
-	return data, nil
-}
-
-// Add function to check for data overlap
-func (s *Scraper) findOverlap(existingData []StockData, newData []StockData) bool {
-	if len(existingData) == 0 || len(newData) == 0 {
-		return false
-	}
-
-	// Compare the most recent date in existing data with the dates in new data
-	mostRecentExisting := existingData[0].Date
-	for _, record := range newData {
-		if record.Date == mostRecentExisting {
-			return true
-		}
-	}
-	return false
-}
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"webscraper/internal/export"
+	"webscraper/internal/metrics"
+	"webscraper/internal/storage"
+	"webscraper/internal/utils"
+	"webscraper/models"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// DefaultBaseURL is the ISX portal root used when no base URL is configured.
+const DefaultBaseURL = "http://www.isx-iq.net/isxportal/portal"
+
+type Scraper struct {
+	logger      *utils.Logger
+	ctx         context.Context
+	cancel      context.CancelFunc
+	config      *utils.Config
+	perfTracker *utils.PerformanceTracker
+
+	// browserCtx is the root browser context that tabs are opened from, and
+	// limiter paces portal requests across all tabs of that browser.
+	browserCtx context.Context
+	limiter    *utils.RateLimiter
+
+	// store holds the saved price history that scrapes are merged into;
+	// exporters additionally write it to exportDir after each save
+	store     storage.Store
+	exporters []export.Exporter
+	exportDir string
+	// metrics, if set, receives step timings, retries and tab refreshes
+	metrics *metrics.Metrics
+
+	// dialogMu guards dialogMessage, and s.logger against the dialog
+	// handler, which runs on chromedp's event goroutine
+	dialogMu      sync.Mutex
+	dialogMessage string
+	dialogCtx     context.Context // tab context the dialog handler is attached to
+
+	// op carries the deadline of the GetStockDataContext call in progress
+	op context.Context
+	// ticker is the ticker being scraped, or the last one scraped in this tab
+	ticker string
+	// tickerInfo holds the company and settings of ticker from the ticker
+	// file, if it was scraped with GetTickerData
+	tickerInfo utils.Ticker
+	// tablePage is the history page last loaded into the results table
+	tablePage int
+	// stats describes the GetStockDataContext call in progress or last run
+	stats ScrapeStats
+	// tickerPerf times the phases of the ticker being scraped until it is
+	// saved, after which it is merged into perfTracker; span is the phase
+	// currently being timed
+	tickerPerf *utils.PerformanceTracker
+	span       *utils.Span
+}
+
+// ScrapeStats describes a single GetStockData call. After a failed call it
+// covers the work done before the failure.
+type ScrapeStats struct {
+	Pages   int // history pages extracted
+	Fetched int // rows read from the portal
+	Added   int // trading days missing from the saved history
+	Revised int // saved trading days whose values changed
+}
+
+func NewScraper(logger *utils.Logger, ctx context.Context, cancel context.CancelFunc, config *utils.Config) *Scraper {
+	return &Scraper{
+		logger:      logger,
+		ctx:         ctx,
+		cancel:      cancel,
+		config:      config,
+		perfTracker: utils.NewPerformanceTracker(),
+		browserCtx:  ctx,
+		limiter:     utils.NewRateLimiter(config.Scraper.RateLimit),
+		store:       storage.NewCSVStore("output", storage.DefaultOptions(), logger),
+		exportDir:   "output",
+	}
+}
+
+// SetStore replaces the store that price history is loaded from and saved to.
+// Tabs opened afterwards share it. The caller remains responsible for closing
+// the store.
+func (s *Scraper) SetStore(store storage.Store) {
+	s.store = store
+}
+
+// SetExporters sets the formats that saved history is also exported to, as
+// <dir>/<TICKER>_data.<ext>. Tabs opened afterwards share them.
+func (s *Scraper) SetExporters(dir string, exporters []export.Exporter) {
+	s.exportDir = dir
+	s.exporters = exporters
+}
+
+// SetMetrics sets the metrics that step timings, retries and tab refreshes are
+// recorded in. Tabs opened afterwards share them.
+func (s *Scraper) SetMetrics(m *metrics.Metrics) {
+	s.metrics = m
+}
+
+// NewTab opens a new tab in the scraper's browser. The returned scraper shares
+// the logger, config, rate limiter, PerformanceTracker and metrics, and can scrape
+// concurrently with other tabs. Close it with CloseTab.
+func (s *Scraper) NewTab() (*Scraper, error) {
+	ctx, cancel := chromedp.NewContext(s.browserCtx)
+	if err := chromedp.Run(ctx, chromedp.Navigate("about:blank")); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open browser tab: %v", err)
+	}
+
+	return &Scraper{
+		logger:      s.logger,
+		ctx:         ctx,
+		cancel:      cancel,
+		config:      s.config,
+		perfTracker: s.perfTracker,
+		browserCtx:  s.browserCtx,
+		limiter:     s.limiter,
+		store:       s.store,
+		exporters:   s.exporters,
+		exportDir:   s.exportDir,
+		metrics:     s.metrics,
+	}, nil
+}
+
+// BrowserAlive reports whether the scraper's browser is still running.
+func (s *Scraper) BrowserAlive() bool {
+	return s.browserCtx.Err() == nil
+}
+
+// CloseTab closes a tab opened with NewTab, leaving the browser running.
+func (s *Scraper) CloseTab() {
+	if s.cancel != nil && s.ctx != s.browserCtx {
+		s.cancel()
+	}
+}
+
+// GetStockData scrapes the price history of ticker, bounded only by the
+// per-ticker timeout.
+func (s *Scraper) GetStockData(ticker string) ([]models.StockData, error) {
+	return s.GetStockDataContext(context.Background(), ticker)
+}
+
+// GetStockDataContext scrapes the price history of ticker. The scrape stops
+// when ctx is done or after the configured per-ticker timeout, whichever comes
+// first; IsTimeout reports whether the returned error was caused by either.
+func (s *Scraper) GetStockDataContext(ctx context.Context, ticker string) ([]models.StockData, error) {
+	return s.GetTickerData(ctx, utils.Ticker{Company: models.Company{Symbol: ticker}, Enabled: true})
+}
+
+// GetTickerData is like GetStockDataContext for a ticker read from the ticker
+// file: its from date, if set, replaces scraper.fromDate, and the following
+// Save of the ticker carries its sector and name into the exports.
+func (s *Scraper) GetTickerData(ctx context.Context, t utils.Ticker) ([]models.StockData, error) {
+	ticker := t.Symbol
+	s.tickerInfo = t
+	op, cancel := context.WithTimeoutCause(ctx, s.tickerTimeout(), ErrTickerTimeout)
+	defer cancel()
+	s.op = op
+	defer func() { s.op = nil }()
+	s.stats = ScrapeStats{}
+	s.ticker = ticker
+
+	// Tag everything logged during the scrape with the ticker
+	logger := s.logger
+	s.setLogger(logger.With("ticker", ticker))
+	defer s.setLogger(logger)
+
+	// Time this ticker on a tracker of its own; Save adds the save phase
+	// and writes its report next to the output
+	s.finishTickerPerf()
+	s.tickerPerf = utils.NewPerformanceTracker()
+
+	endStep := s.startStep("get stock data")
+	data, err := s.getStockData(ticker)
+	endStep()
+	if err != nil {
+		s.finishTickerPerf()
+		if cause := s.opErr(); cause != nil && !errors.Is(err, cause) {
+			return nil, fmt.Errorf("%w: %v", cause, err)
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s *Scraper) getStockData(ticker string) ([]models.StockData, error) {
+	from := s.config.Scraper.FromDate
+	if s.tickerInfo.FromDate != "" {
+		from = s.tickerInfo.FromDate
+	}
+	fromDate, toDate, err := utils.ResolveDateRange(from, s.config.Scraper.ToDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date range: %v", err)
+	}
+	from = fromDate.Format(models.PortalDateLayout)
+	to := toDate.Format(models.PortalDateLayout)
+
+	// Try to load existing data
+	endStep := s.startStep("load existing")
+	existingData, err := s.loadExistingData(ticker)
+	endStep()
+	if err != nil {
+		s.logger.Debug("Error loading existing data: %v", err)
+		// Continue with full scrape if there's an error
+	}
+
+	s.logger.Info("Starting data extraction for ticker: %s (%s - %s)", ticker, from, to)
+	query := historyQuery{ticker: ticker, from: from, to: to}
+
+	// Navigate to the page
+	endStep = s.startStep("navigate")
+	err = s.withRetry("navigate", func(int) error {
+		return s.openProfile(ticker)
+	})
+	endStep()
+	if err != nil {
+		return nil, fmt.Errorf("failed to navigate: %w", err)
+	}
+
+	// Set up date range and trigger search
+	endStep = s.startStep("set date range")
+	err = s.withRetry("set date range", func(attempt int) error {
+		if attempt > 1 {
+			if err := s.ensureProfile(ticker); err != nil {
+				return err
+			}
+		}
+		return s.searchDateRange(query)
+	})
+	endStep()
+	if err != nil {
+		return nil, fmt.Errorf("failed to set date range: %w", err)
+	}
+
+	// Pages are newest first, so once a page reaches back to the newest saved
+	// date everything older is already on disk and paging can stop.
+	latestSaved := latestDate(existingData)
+
+	var allStockData []models.StockData
+	currentPage := 1
+	maxPages := s.config.Scraper.MaxPages
+	foundOverlap := false
+	previousPageCount := 0 // Track previous page record count
+
+	s.logger.Debug("Starting data extraction, will process up to %d pages", maxPages)
+
+	for currentPage <= maxPages && !foundOverlap {
+		// Extract data from current page, reloading just this page on retry
+		var rawPage []models.RawRecord
+		endStep = s.startStep("extract page")
+		err = s.withRetry(fmt.Sprintf("extract page %d", currentPage), func(attempt int) error {
+			if attempt > 1 {
+				if err := s.ensureProfile(ticker); err != nil {
+					return err
+				}
+				if err := s.loadPage(query, currentPage); err != nil {
+					return err
+				}
+			}
+			var err error
+			rawPage, err = s.extractPage()
+			return err
+		})
+		endStep()
+		if err != nil {
+			s.logger.With("page", currentPage).Error("Error extracting data from page %d: %v", currentPage, err)
+			return nil, fmt.Errorf("failed to extract data from page %d: %w", currentPage, err)
+		}
+
+		pageData, err := models.ParseRecords(rawPage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse page %d: %w", currentPage, err)
+		}
+		s.stats.Pages = currentPage
+		s.stats.Fetched += len(pageData)
+
+		// Check if we've reached the end of data
+		if len(pageData) == 0 {
+			s.logger.Debug("No more data found on page %d, stopping extraction", currentPage)
+			break
+		}
+
+		// Check if we got fewer records than the previous page (last page usually has fewer records)
+		if previousPageCount > 0 && len(pageData) < previousPageCount {
+			s.logger.Debug("Found last page with %d records (previous had %d)", len(pageData), previousPageCount)
+		}
+		previousPageCount = len(pageData)
+
+		s.logger.With("page", currentPage).Info("Successfully extracted %d records from page %d", len(pageData), currentPage)
+
+		if len(existingData) > 0 {
+			foundOverlap = reachesDate(pageData, latestSaved)
+			if foundOverlap {
+				s.logger.Debug("Page %d reaches back to saved data from %s", currentPage, latestSaved.Format(models.DateLayout))
+			}
+		}
+
+		allStockData = append(allStockData, pageData...)
+
+		if foundOverlap {
+			s.logger.Info("Found overlap with existing data, stopping extraction")
+			break
+		}
+
+		// Check if we've reached the end of data
+		if len(pageData) < 25 { // Assuming 25 is the standard page size
+			s.logger.Debug("Reached last page (incomplete page), stopping extraction")
+			break
+		}
+
+		if currentPage >= maxPages {
+			break
+		}
+
+		// Navigate to next page
+		nextPage := currentPage + 1
+		s.logger.With("page", nextPage).Debug("Navigating to page %d", nextPage)
+		endStep = s.startStep("paginate")
+		err = s.withRetry(fmt.Sprintf("paginate to page %d", nextPage), func(attempt int) error {
+			if attempt > 1 {
+				if err := s.ensureProfile(ticker); err != nil {
+					return err
+				}
+			}
+			return s.loadPage(query, nextPage)
+		})
+		endStep()
+		if err != nil {
+			s.logger.With("page", nextPage).Error("Failed to navigate to page %d: %v", nextPage, err)
+			return nil, fmt.Errorf("failed to navigate to page %d: %w", nextPage, err)
+		}
+
+		currentPage++
+	}
+
+	// Merge with existing data by trading date; scraped rows replace saved
+	// rows for the same day
+	endStep = s.startStep("calculate changes")
+	defer endStep()
+	merged := models.Merge(existingData, allStockData)
+	s.logMerge(ticker, merged)
+	s.stats.Added = merged.Added
+	s.stats.Revised = len(merged.Revised)
+
+	// Calculate changes for all data
+	return s.calculatePriceChanges(merged.Data), nil
+}
+
+// startStep starts timing a phase of the current ticker's scrape, nested in
+// the phase already being timed, and returns the function that ends it. The
+// duration is also recorded in the scraper's metrics. Outside of a scrape it
+// does nothing.
+func (s *Scraper) startStep(name string) func() {
+	if s.tickerPerf == nil {
+		return func() {}
+	}
+
+	parent := s.span
+	if parent != nil {
+		s.span = parent.Start(name)
+	} else {
+		s.span = s.tickerPerf.Start(name)
+	}
+	span := s.span
+	ticker := s.ticker
+	return func() {
+		s.metrics.ObserveStep(ticker, name, span.End())
+		s.span = parent
+	}
+}
+
+// finishTickerPerf folds the current ticker's timings into the scraper's
+// tracker, which aggregates all tickers scraped in this tab.
+func (s *Scraper) finishTickerPerf() {
+	if s.tickerPerf == nil {
+		return
+	}
+	s.perfTracker.Merge(s.tickerPerf)
+	s.tickerPerf = nil
+	s.span = nil
+}
+
+// savePerfReport writes the step timings of the current ticker to
+// <exportDir>/<TICKER>_perf.txt.
+func (s *Scraper) savePerfReport(ticker string) {
+	if s.tickerPerf == nil {
+		return
+	}
+	if err := os.MkdirAll(s.exportDir, 0755); err != nil {
+		s.logger.Error("Failed to create %s: %v", s.exportDir, err)
+		return
+	}
+	path := filepath.Join(s.exportDir, fmt.Sprintf("%s_perf.txt", ticker))
+	report := fmt.Sprintf("Ticker: %s\nGenerated: %s\n%s", ticker,
+		time.Now().Format(time.RFC3339), s.tickerPerf.GenerateReport())
+	if err := os.WriteFile(path, []byte(report), 0644); err != nil {
+		s.logger.Error("Failed to write performance report %s: %v", path, err)
+		return
+	}
+	s.logger.Debug("Performance report written to %s", path)
+}
+
+// historyQuery identifies the price history being paged through.
+type historyQuery struct {
+	ticker string
+	from   string
+	to     string
+}
+
+// openProfile loads the ticker's company profile page in the current tab,
+// configuring request blocking and the dialog handler first.
+func (s *Scraper) openProfile(ticker string) error {
+	// Disable image loading before navigation
+	endStep := s.startStep("configure network")
+	err := s.run(
+		network.Enable(),
+		emulation.SetCPUThrottlingRate(1),
+		network.SetExtraHTTPHeaders(map[string]interface{}{
+			"Accept": "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+		}),
+		network.SetBlockedURLS([]string{
+			"*.png",
+			"*.jpg",
+			"*.jpeg",
+			"*.gif",
+			"*.webp",
+			"*.svg",
+			"*.ico",
+		}),
+	)
+	endStep()
+	if err != nil {
+		s.logger.Debug("Failed to set image blocking: %v", err)
+		// Continue anyway as this is not critical
+	}
+
+	// Add dialog handler before navigation, once per tab
+	if s.dialogCtx != s.ctx {
+		s.dialogCtx = s.ctx
+		ctx := s.ctx
+		chromedp.ListenTarget(ctx, func(ev interface{}) {
+			if ev, ok := ev.(*page.EventJavascriptDialogOpening); ok {
+				logger := s.dialogLogger()
+				logger.Debug("Dialog detected: %s", ev.Message)
+				s.setDialogMessage(ev.Message)
+				go func() {
+					if err := chromedp.Run(ctx,
+						page.HandleJavaScriptDialog(true),
+					); err != nil {
+						logger.Debug("Failed to handle dialog: %v", err)
+					}
+				}()
+			}
+		})
+	}
+
+	url := fmt.Sprintf("%s/companyprofilecontainer.html?currLanguage=en&companyCode=%s%%20&activeTab=0", s.baseURL(), ticker)
+	if err := s.waitForSlot(); err != nil {
+		return err
+	}
+	s.tablePage = 0
+	defer s.startStep("load profile page")()
+	return s.run(
+		chromedp.Navigate(url),
+		chromedp.WaitReady("body"),
+	)
+}
+
+// ensureProfile makes sure the tab still shows a profile page with the portal
+// scripts loaded, navigating back to it after a failure or tab refresh.
+func (s *Scraper) ensureProfile(ticker string) error {
+	var ready bool
+	err := s.run(
+		chromedp.Evaluate(`typeof doAjax === 'function' && document.getElementById('ajxDspId') !== null`, &ready),
+	)
+	if err == nil && ready {
+		return nil
+	}
+	s.logger.Debug("Profile page for %s not loaded, navigating again", ticker)
+	return s.openProfile(ticker)
+}
+
+// searchDateRange fills in the date filter and runs the search.
+func (s *Scraper) searchDateRange(query historyQuery) error {
+	s.setDialogMessage("")
+	if _, err := s.markTableStale(); err != nil {
+		return err
+	}
+	err := s.run(
+		chromedp.Evaluate(fmt.Sprintf(`
+			(() => {
+				const setDate = (selector, value) => {
+					const dateInput = document.querySelector(selector);
+					if (!dateInput) {
+						return;
+					}
+					dateInput.value = value;
+					const event = new Event('change', { bubbles: true });
+					dateInput.dispatchEvent(event);
+				};
+				setDate("#fromDate", %q);
+				setDate("#toDate", %q);
+
+				const searchButton = document.querySelector("#command > div.filterbox > div.button-all > input[type=button]");
+				searchButton.click();
+				return true;
+			})()
+		`, query.from, query.to), nil),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Wait for the search results to replace the table. Any table shown
+	// before the search may legitimately hold the same rows, so only the
+	// replacement itself is checked. The portal reports invalid ranges
+	// through an alert dialog instead of a table.
+	if err := s.waitForTable(1, tableState{}); err != nil {
+		if msg := s.takeDialogMessage(); msg != "" {
+			return fatal(fmt.Errorf("portal rejected date range %s - %s: %s", query.from, query.to, msg))
+		}
+		return fmt.Errorf("no results for date range %s - %s: %w", query.from, query.to, err)
+	}
+	return nil
+}
+
+// loadPage requests history page n through the portal's doAjax helper, which
+// replaces the results table in place.
+func (s *Scraper) loadPage(query historyQuery, n int) error {
+	if err := s.waitForSlot(); err != nil {
+		return err
+	}
+	previous, err := s.markTableStale()
+	if err != nil {
+		return err
+	}
+	params := url.Values{
+		"fromDate":    {query.from},
+		"d-6716032-p": {strconv.Itoa(n)},
+		"toDate":      {query.to},
+		"companyCode": {query.ticker},
+	}
+	err = s.run(
+		chromedp.Evaluate(fmt.Sprintf(`
+			(() => {
+				doAjax('companyperformancehistoryfilter.html', %q, 'ajxDspId');
+				return true;
+			})()
+		`, params.Encode()), nil),
+	)
+	if err != nil {
+		return err
+	}
+
+	return s.waitForTable(n, previous)
+}
+
+// extractPage reads the rows of the results table currently on the page.
+func (s *Scraper) extractPage() ([]models.RawRecord, error) {
+	var result struct {
+		Found bool
+		Rows  []models.RawRecord
+	}
+	err := s.run(
+		chromedp.Evaluate(`
+			(() => {
+				const table = document.getElementById('dispTable');
+				if (!table) {
+					return { Found: false, Rows: [] };
+				}
+				const rows = table.querySelectorAll('tbody tr');
+				return {
+					Found: true,
+					Rows: Array.from(rows).map(row => {
+						const cells = row.querySelectorAll('td');
+						return {
+							Date: cells[9].textContent.trim(),
+							OpenPrice: cells[7].textContent.trim(),
+							HighPrice: cells[6].textContent.trim(),
+							LowPrice: cells[5].textContent.trim(),
+							ClosePrice: cells[8].textContent.trim(),
+							Volume: cells[1].textContent.trim(),
+							TotalShares: cells[2].textContent.trim(),
+							NumTrades: cells[0].textContent.trim()
+						};
+					})
+				};
+			})()
+		`, &result),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Found {
+		return nil, ErrTableMissing
+	}
+	return result.Rows, nil
+}
+
+// baseURL returns the configured portal root without a trailing slash.
+func (s *Scraper) baseURL() string {
+	if s.config.Scraper.BaseURL == "" {
+		return DefaultBaseURL
+	}
+	return strings.TrimRight(s.config.Scraper.BaseURL, "/")
+}
+
+// setLogger replaces s.logger while the dialog handler may be reading it.
+func (s *Scraper) setLogger(logger *utils.Logger) {
+	s.dialogMu.Lock()
+	defer s.dialogMu.Unlock()
+	s.logger = logger
+}
+
+// dialogLogger returns s.logger for use by the dialog handler.
+func (s *Scraper) dialogLogger() *utils.Logger {
+	s.dialogMu.Lock()
+	defer s.dialogMu.Unlock()
+	return s.logger
+}
+
+func (s *Scraper) setDialogMessage(msg string) {
+	s.dialogMu.Lock()
+	defer s.dialogMu.Unlock()
+	s.dialogMessage = msg
+}
+
+func (s *Scraper) takeDialogMessage() string {
+	s.dialogMu.Lock()
+	defer s.dialogMu.Unlock()
+	msg := s.dialogMessage
+	s.dialogMessage = ""
+	return msg
+}
+
+// Save saves ticker's history to the scraper's store, which is a CSV file per
+// ticker in output/ unless another store was set with SetStore, and then
+// writes it in each export format set with SetExporters. After a successful
+// save, the step timings of the ticker's scrape are written to
+// <TICKER>_perf.txt in the export directory.
+func (s *Scraper) Save(ticker string, data []models.StockData) error {
+	defer s.finishTickerPerf()
+
+	endStep := s.startStep("save")
+	err := s.save(ticker, data)
+	endStep()
+	if err != nil {
+		return err
+	}
+
+	s.savePerfReport(ticker)
+	return nil
+}
+
+func (s *Scraper) save(ticker string, data []models.StockData) error {
+	if len(data) == 0 {
+		return fmt.Errorf("no data to save")
+	}
+
+	if err := s.store.Save(ticker, data); err != nil {
+		return fmt.Errorf("%w: %w", ErrSave, err)
+	}
+	location := s.store.Location(ticker)
+	s.logger.Info("Successfully saved data to %s", location)
+
+	company := models.Company{Symbol: ticker}
+	if s.tickerInfo.Symbol == ticker {
+		company = s.tickerInfo.Company
+	}
+
+	for _, exporter := range s.exporters {
+		// A CSV export into the CSV store's directory is the stored file itself
+		if filepath.Clean(export.Path(exporter, s.exportDir, ticker)) == filepath.Clean(location) {
+			continue
+		}
+		path, err := export.WriteFile(exporter, s.exportDir, company, data)
+		if err != nil {
+			return fmt.Errorf("%w: %s export failed: %v", ErrSave, exporter.Name(), err)
+		}
+		s.logger.Info("Exported %s data to %s", exporter.Name(), path)
+	}
+	return nil
+}
+
+func (s *Scraper) Close() {
+	s.logger.Info("Closing browser")
+	if s.cancel != nil {
+		// Create a new context with a short timeout for cleanup
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		// Try to close gracefully
+		if err := chromedp.Run(ctx, chromedp.Stop()); err != nil {
+			s.logger.Error("Error during graceful shutdown: %v", err)
+		}
+
+		s.cancel()
+		time.Sleep(s.config.Scraper.Waits.BrowserClose.D())
+		s.logger.Info("Browser closed successfully")
+	}
+}
+
+// LastStats returns the statistics of the most recent GetStockData call.
+func (s *Scraper) LastStats() ScrapeStats {
+	return s.stats
+}
+
+func (s *Scraper) GetPerformanceTracker() *utils.PerformanceTracker {
+	return s.perfTracker
+}
+
+// ResetPerformanceTracker replaces the scraper's PerformanceTracker with an
+// empty one, so a long-running process reports each batch on its own. Tabs
+// opened afterwards share the new tracker; call it while no tab is scraping.
+func (s *Scraper) ResetPerformanceTracker() {
+	s.perfTracker = utils.NewPerformanceTracker()
+}
+
+// PreflightResult is the outcome of one preflight check.
+type PreflightResult struct {
+	Name string
+	Err  error
+}
+
+// preflightChecks lists the checks run before scraping, in order.
+func (s *Scraper) preflightChecks() []struct {
+	name  string
+	check func() error
+} {
+	return []struct {
+		name  string
+		check func() error
+	}{
+		{"Config Validation", s.validateConfig},
+		{"Directory Structure", s.checkDirectories},
+		{"Browser Launch", s.testBrowserLaunch},
+		{"Network Settings", s.testNetworkSettings},
+	}
+}
+
+// PreflightCheck verifies all dependencies and configurations
+func (s *Scraper) PreflightCheck() error {
+	for _, c := range s.preflightChecks() {
+		s.logger.Debug("Running preflight check: %s", c.name)
+		if err := c.check(); err != nil {
+			return fmt.Errorf("%s check failed: %v", c.name, err)
+		}
+		s.logger.Debug("%s check passed", c.name)
+	}
+
+	return nil
+}
+
+// RunPreflightChecks runs every preflight check, even after one fails, and
+// returns their results in order.
+func (s *Scraper) RunPreflightChecks() []PreflightResult {
+	checks := s.preflightChecks()
+	results := make([]PreflightResult, 0, len(checks))
+	for _, c := range checks {
+		results = append(results, PreflightResult{Name: c.name, Err: c.check()})
+	}
+	return results
+}
+
+func (s *Scraper) validateConfig() error {
+	if s.config == nil {
+		return fmt.Errorf("configuration is nil")
+	}
+	if s.config.Scraper.Timeout <= 0 {
+		return fmt.Errorf("invalid timeout value")
+	}
+	if s.config.Scraper.MaxPages <= 0 {
+		return fmt.Errorf("invalid max pages value")
+	}
+	if _, _, err := utils.ResolveDateRange(s.config.Scraper.FromDate, s.config.Scraper.ToDate); err != nil {
+		return fmt.Errorf("invalid date range: %v", err)
+	}
+	return nil
+}
+
+func (s *Scraper) checkDirectories() error {
+	dirs := []string{
+		"output",
+		s.config.Logging.Dir,
+		"temp_builds",
+	}
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s directory: %v", dir, err)
+		}
+	}
+	return nil
+}
+
+func (s *Scraper) testBrowserLaunch() error {
+	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+	defer cancel()
+
+	return chromedp.Run(ctx, chromedp.Navigate("about:blank"))
+}
+
+func (s *Scraper) testNetworkSettings() error {
+	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+	defer cancel()
+
+	return chromedp.Run(ctx,
+		network.Enable(),
+		network.SetCacheDisabled(true),
+		emulation.SetUserAgentOverride("Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/91.0.4472.124 Safari/537.36"),
+	)
+}
+
+// RefreshTab replaces the scraper's tab with a fresh one in the same browser.
+// Only tabs opened with NewTab can be refreshed.
+func (s *Scraper) RefreshTab() error {
+	return s.RefreshTabContext(context.Background())
+}
+
+// RefreshTabContext is like RefreshTab, but cuts the wait for the browser to
+// settle short when ctx is done or the scrape in progress hits its deadline.
+func (s *Scraper) RefreshTabContext(ctx context.Context) error {
+	if s.ctx == s.browserCtx {
+		return fmt.Errorf("cannot refresh the root browser context")
+	}
+	s.logger.Debug("Refreshing browser tab")
+	s.metrics.BrowserRefreshed(s.ticker)
+
+	// Close the old tab
+	s.cancel()
+
+	// Open a new tab in the same browser
+	tabCtx, cancel := chromedp.NewContext(s.browserCtx)
+	s.ctx = tabCtx
+	s.cancel = cancel
+
+	// Test new tab
+	err := chromedp.Run(tabCtx, chromedp.Navigate("about:blank"))
+	if err != nil {
+		return fmt.Errorf("failed to refresh browser: %v", err)
+	}
+
+	// Give the browser time to settle before the tab is used
+	s.sleep(ctx, s.config.Scraper.Waits.AfterRefresh.D())
+	return nil
+}
+
+// Add calculation function
+func (s *Scraper) calculatePriceChanges(data []models.StockData) []models.StockData {
+	if len(data) < 2 {
+		return data
+	}
+
+	// Process each day's data starting from most recent (data is in reverse chronological order)
+	for i := 0; i < len(data)-1; i++ {
+		currentClose := data[i].ClosePrice
+		previousClose := data[i+1].ClosePrice
+		if currentClose == 0 || previousClose == 0 {
+			s.logger.Debug("Skipping change for %s: no close price", data[i].Date.Format(models.PortalDateLayout))
+			continue
+		}
+
+		// Calculate change and change percentage
+		data[i].Change = currentClose - previousClose
+		data[i].ChangePerc = data[i].Change.Float64() / previousClose.Float64() * 100
+	}
+
+	return data
+}
+
+// loadExistingData returns the saved history of ticker from the store.
+func (s *Scraper) loadExistingData(ticker string) ([]models.StockData, error) {
+	return s.store.Load(ticker)
+}
+
+// latestDate returns the newest trading date in data, or the zero time when
+// data is empty.
+func latestDate(data []models.StockData) time.Time {
+	var latest time.Time
+	for _, record := range data {
+		if record.Date.After(latest) {
+			latest = record.Date
+		}
+	}
+	return latest
+}
+
+// reachesDate reports whether any record in page is on or before date.
+func reachesDate(page []models.StockData, date time.Time) bool {
+	for _, record := range page {
+		if !record.Date.After(date) {
+			return true
+		}
+	}
+	return false
+}
+
+// logMerge reports what merging the scrape into the saved history changed.
+// Revised rows are logged individually since they mean the portal's history
+// differs from what was saved earlier.
+func (s *Scraper) logMerge(ticker string, merged models.MergeResult) {
+	for _, rev := range merged.Revised {
+		s.logger.Info("%s: revised row for %s: close %s -> %s, volume %d -> %d",
+			ticker, rev.Date.Format(models.DateLayout),
+			rev.Old.ClosePrice, rev.New.ClosePrice, rev.Old.Volume, rev.New.Volume)
+	}
+	if merged.Duplicates > 0 {
+		s.logger.Info("%s: dropped %d duplicate rows", ticker, merged.Duplicates)
+	}
+	s.logger.Info("%s: %d new rows, %d revised, %d total",
+		ticker, merged.Added, len(merged.Revised), len(merged.Data))
+}
@@ -0,0 +1,206 @@
+package scraper
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"webscraper/internal/replay"
+	"webscraper/internal/storage"
+	"webscraper/internal/utils"
+	"webscraper/models"
+
+	"github.com/chromedp/chromedp"
+)
+
+// replayDir holds the saved portal pages served by the replay server.
+var replayDir = filepath.Join("..", "..", "test", "testdata", "replay")
+
+// newReplayScraper starts a replay server for replayDir and a headless
+// browser, and returns a scraper pointed at the server. The test is skipped
+// when no Chrome can be started; CHROME_PATH selects the binary to use.
+func newReplayScraper(t *testing.T) (*Scraper, *replay.Server) {
+	t.Helper()
+
+	server, err := replay.NewServer(replayDir)
+	if err != nil {
+		t.Fatalf("failed to start replay server: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	opts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.NoSandbox, chromedp.DisableGPU)
+	if path := os.Getenv("CHROME_PATH"); path != "" {
+		opts = append(opts, chromedp.ExecPath(path))
+	}
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), opts...)
+	t.Cleanup(cancelAlloc)
+	ctx, cancel := chromedp.NewContext(allocCtx)
+	t.Cleanup(cancel)
+	if err := chromedp.Run(ctx, chromedp.Navigate("about:blank")); err != nil {
+		t.Skipf("Chrome is not available: %v", err)
+	}
+
+	dir := t.TempDir()
+	logger, err := utils.NewLogger(utils.LoggingConfig{Level: "debug", Dir: filepath.Join(dir, "logs"), Console: io.Discard})
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	t.Cleanup(func() { logger.Close() })
+
+	config := &utils.Config{}
+	config.Scraper.Timeout = 60
+	config.Scraper.Retries = 1
+	config.Scraper.MaxPages = 5
+	config.Scraper.FromDate = "2024-11-01"
+	config.Scraper.ToDate = "2024-12-31"
+	config.Scraper.BaseURL = server.BaseURL()
+	config.Scraper.Waits.TableLoad = utils.Duration(15 * time.Second)
+	config.Logging.Dir = filepath.Join(dir, "logs")
+
+	s := NewScraper(logger, ctx, cancel, config)
+	s.SetStore(storage.NewCSVStore(filepath.Join(dir, "output"), storage.DefaultOptions(), logger))
+	s.SetExporters(filepath.Join(dir, "output"), nil)
+	return s, server
+}
+
+func TestGetStockDataReplay(t *testing.T) {
+	s, server := newReplayScraper(t)
+
+	data, err := s.GetStockData("BBOB")
+	if err != nil {
+		t.Fatalf("GetStockData failed: %v", err)
+	}
+
+	// Page 1 holds 25 rows from 23/12/2024 back, page 2 the last 10
+	if len(data) != 35 {
+		t.Fatalf("got %d rows, want 35", len(data))
+	}
+	if stats := s.LastStats(); stats.Pages != 2 || stats.Fetched != 35 || stats.Added != 35 {
+		t.Errorf("got stats %+v, want 2 pages, 35 fetched and added", stats)
+	}
+
+	newest := time.Date(2024, 12, 23, 0, 0, 0, 0, time.UTC)
+	if !data[0].Date.Equal(newest) {
+		t.Errorf("newest row is dated %s, want %s", data[0].Date.Format(models.DateLayout), newest.Format(models.DateLayout))
+	}
+	for i := 1; i < len(data); i++ {
+		if !data[i].Date.Before(data[i-1].Date) {
+			t.Errorf("row %d (%s) is not older than row %d (%s)", i, data[i].Date.Format(models.DateLayout), i-1, data[i-1].Date.Format(models.DateLayout))
+		}
+	}
+
+	// The first row of page 2, read from the dispTable columns
+	want := models.StockData{
+		Date:        time.Date(2024, 11, 18, 0, 0, 0, 0, time.UTC),
+		OpenPrice:   4160,
+		HighPrice:   4160,
+		LowPrice:    4150,
+		ClosePrice:  4150,
+		Volume:      56156975,
+		TotalShares: 33019720,
+		NumTrades:   132,
+	}
+	var got *models.StockData
+	for i := range data {
+		if data[i].Date.Equal(want.Date) {
+			got = &data[i]
+		}
+	}
+	if got == nil {
+		t.Fatalf("no row for %s from page 2", want.Date.Format(models.DateLayout))
+	}
+	if got.OpenPrice != want.OpenPrice || got.HighPrice != want.HighPrice || got.LowPrice != want.LowPrice ||
+		got.ClosePrice != want.ClosePrice || got.Volume != want.Volume || got.TotalShares != want.TotalShares ||
+		got.NumTrades != want.NumTrades {
+		t.Errorf("got row %+v, want %+v", *got, want)
+	}
+	oldest := data[len(data)-1]
+	if want := time.Date(2024, 11, 5, 0, 0, 0, 0, time.UTC); !oldest.Date.Equal(want) {
+		t.Errorf("oldest row is dated %s, want %s", oldest.Date.Format(models.DateLayout), want.Format(models.DateLayout))
+	}
+
+	pages := map[int]bool{}
+	for _, req := range server.Requests() {
+		if req.Page == "history" && req.Ticker == "BBOB" {
+			pages[req.Number] = true
+		}
+	}
+	if !pages[1] || !pages[2] || pages[3] {
+		t.Errorf("got history pages %v requested, want 1 and 2", pages)
+	}
+}
+
+func TestGetStockDataReplayStopsAtSavedData(t *testing.T) {
+	s, server := newReplayScraper(t)
+
+	// Saved history reaching into page 1 makes page 2 unnecessary
+	saved := []models.StockData{{
+		Date:        time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+		OpenPrice:   4000,
+		HighPrice:   4000,
+		LowPrice:    4000,
+		ClosePrice:  4000,
+		Volume:      1,
+		TotalShares: 1,
+		NumTrades:   1,
+	}}
+	if err := s.store.Save("BBOB", saved); err != nil {
+		t.Fatalf("failed to save history: %v", err)
+	}
+
+	data, err := s.GetStockData("BBOB")
+	if err != nil {
+		t.Fatalf("GetStockData failed: %v", err)
+	}
+	if stats := s.LastStats(); stats.Pages != 1 || stats.Fetched != 25 {
+		t.Errorf("got stats %+v, want 1 page with 25 rows", stats)
+	}
+	for _, req := range server.Requests() {
+		if req.Page == "history" && req.Number > 1 {
+			t.Errorf("requested history page %d after reaching saved data", req.Number)
+		}
+	}
+	if len(data) < 25 {
+		t.Errorf("got %d rows, want page 1 merged with saved history", len(data))
+	}
+}
+
+func TestSleepReturnsWhenContextDone(t *testing.T) {
+	s := &Scraper{ctx: context.Background()}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	s.sleep(ctx, time.Minute)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("sleep with a cancelled context took %v", elapsed)
+	}
+}
+
+func TestRefreshTabContextCancelled(t *testing.T) {
+	s, _ := newReplayScraper(t)
+	s.config.Scraper.Waits.AfterRefresh = utils.Duration(time.Minute)
+
+	tab, err := s.NewTab()
+	if err != nil {
+		t.Fatalf("NewTab failed: %v", err)
+	}
+	defer tab.CloseTab()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	if err := tab.RefreshTabContext(ctx); err != nil {
+		t.Fatalf("RefreshTabContext failed: %v", err)
+	}
+	// Opening the tab takes a moment; the minute-long settle wait must not
+	if elapsed := time.Since(start); elapsed > 20*time.Second {
+		t.Errorf("RefreshTabContext with a cancelled context took %v", elapsed)
+	}
+	if tab.ctx.Err() != nil {
+		t.Error("refreshed tab is already closed")
+	}
+}
@@ -0,0 +1,110 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ErrPageNotChanged is returned when the results table was not replaced with
+// the requested page before the tableLoad wait ran out.
+var ErrPageNotChanged = errors.New("results table did not change")
+
+// tablePollInterval is how often the page is checked while waiting for the
+// results table to change.
+const tablePollInterval = 100 * time.Millisecond
+
+// tableState describes the results table currently on the page.
+type tableState struct {
+	Found     bool
+	FirstDate string
+	Page      int // page number shown by the pager, 0 when there is none
+}
+
+// markTableStale tags the current results table so that waitForTable can tell
+// it apart from the table the portal loads next, and returns its state.
+func (s *Scraper) markTableStale() (tableState, error) {
+	return s.readTable(true)
+}
+
+// readTable returns the state of the current results table, optionally
+// marking it stale. Without a pager on the page, the page number is taken
+// from the last page waitForTable saw load.
+func (s *Scraper) readTable(markStale bool) (tableState, error) {
+	var state tableState
+	err := s.run(
+		chromedp.Evaluate(fmt.Sprintf(`
+			(() => {
+				const table = document.getElementById('dispTable');
+				if (!table) {
+					return { Found: false, FirstDate: '', Page: 0 };
+				}
+				if (%t) {
+					table.setAttribute('data-scraper-stale', '1');
+				}
+				const firstRow = table.querySelector('tbody tr');
+				const firstDate = firstRow && firstRow.cells.length > 9 ? firstRow.cells[9].textContent.trim() : '';
+				const current = document.querySelector('.pagelinks strong');
+				return {
+					Found: true,
+					FirstDate: firstDate,
+					Page: current ? parseInt(current.textContent, 10) || 0 : 0
+				};
+			})()
+		`, markStale), &state),
+	)
+	if err == nil && state.Found && state.Page == 0 {
+		state.Page = s.tablePage
+	}
+	return state, err
+}
+
+// waitForTable waits until the stale table has been replaced by a new one
+// showing page n, bounded by the configured tableLoad wait. When the previous
+// table held a different page, the new table must also start on a different
+// date, which catches the portal serving the old page again.
+func (s *Scraper) waitForTable(n int, previous tableState) error {
+	timeout := s.config.Scraper.Waits.TableLoad.D()
+
+	var ready bool
+	err := s.run(
+		chromedp.Poll(fmt.Sprintf(`
+			(() => {
+				const table = document.getElementById('dispTable');
+				if (!table || table.hasAttribute('data-scraper-stale')) {
+					return false;
+				}
+				const current = document.querySelector('.pagelinks strong');
+				return !current || parseInt(current.textContent, 10) === %d;
+			})()
+		`, n), &ready,
+			chromedp.WithPollingInterval(tablePollInterval),
+			chromedp.WithPollingTimeout(timeout),
+		),
+	)
+	if err != nil {
+		if opErr := s.opErr(); opErr != nil {
+			return opErr
+		}
+		if errors.Is(err, chromedp.ErrPollingTimeout) {
+			return fmt.Errorf("page %d not loaded after %v: %w", n, timeout, ErrPageNotChanged)
+		}
+		return err
+	}
+
+	if previous.Found && previous.Page != n && previous.FirstDate != "" {
+		current, err := s.readTable(false)
+		if err != nil {
+			return err
+		}
+		if current.FirstDate == previous.FirstDate {
+			return fmt.Errorf("page %d still starts on %s like page %d: %w",
+				n, current.FirstDate, previous.Page, ErrPageNotChanged)
+		}
+	}
+
+	s.tablePage = n
+	return nil
+}
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"webscraper/internal/utils"
+	"webscraper/models"
+)
+
+// ErrHistoryShrunk is returned when a save would replace saved history with
+// fewer trading days than it already holds. Merged scrapes only ever add
+// days, so this points at a broken scrape or a damaged baseline.
+var ErrHistoryShrunk = errors.New("refusing to shrink saved history")
+
+// CSVStore keeps each ticker's history in <dir>/<TICKER>_data.csv. Every save
+// rewrites the whole file atomically, keeping the previous versions as
+// backups.
+type CSVStore struct {
+	dir     string
+	options Options
+	logger  *utils.Logger
+}
+
+// NewCSVStore creates a store writing CSV files into dir.
+func NewCSVStore(dir string, options Options, logger *utils.Logger) *CSVStore {
+	return &CSVStore{dir: dir, options: options, logger: logger}
+}
+
+// Path returns the CSV file holding ticker's history.
+func (c *CSVStore) Path(ticker string) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%s_data.csv", ticker))
+}
+
+func (c *CSVStore) Location(ticker string) string {
+	return c.Path(ticker)
+}
+
+func (c *CSVStore) Tickers() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(c.dir, "*_data.csv"))
+	if err != nil {
+		return nil, err
+	}
+	tickers := make([]string, 0, len(matches))
+	for _, path := range matches {
+		tickers = append(tickers, strings.TrimSuffix(filepath.Base(path), "_data.csv"))
+	}
+	sort.Strings(tickers)
+	return tickers, nil
+}
+
+func (c *CSVStore) Load(ticker string) ([]models.StockData, error) {
+	data, version, err := c.read(ticker)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil && version != c.schema() && c.logger != nil {
+		c.logger.Info("%s uses CSV schema v%d, it will be rewritten as v%d", c.Path(ticker), version, c.schema())
+	}
+	return data, nil
+}
+
+// schema returns the CSV schema version the store writes.
+func (c *CSVStore) schema() int {
+	if c.options.CSVSchema == 0 {
+		return models.SchemaVersion
+	}
+	return c.options.CSVSchema
+}
+
+// read returns the saved history of ticker and its CSV schema version.
+func (c *CSVStore) read(ticker string) ([]models.StockData, int, error) {
+	filename := c.Path(ticker)
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, 0, nil // No saved history yet
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	data, version, err := models.ReadCSV(file)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %v", filename, err)
+	}
+	return data, version, nil
+}
+
+func (c *CSVStore) Save(ticker string, data []models.StockData) error {
+	// Create the output directory if it doesn't exist
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	filename := c.Path(ticker)
+	if !c.options.Force {
+		if err := c.checkShrink(ticker, data); err != nil {
+			return err
+		}
+	}
+
+	// Write data in the configured schema version
+	return utils.WriteFileAtomic(filename, c.options.Backups, func(w io.Writer) error {
+		return models.WriteCSVVersion(w, data, c.schema())
+	})
+}
+
+// checkShrink compares data with the saved history and fails with
+// ErrHistoryShrunk when data covers fewer trading days. Duplicate days in
+// the saved file are not counted.
+func (c *CSVStore) checkShrink(ticker string, data []models.StockData) error {
+	saved, _, err := c.read(ticker)
+	if err != nil {
+		// An unreadable baseline is exactly what the backups are for
+		if c.logger != nil {
+			c.logger.Error("Cannot validate against saved history of %s, replacing it: %v", ticker, err)
+		}
+		return nil
+	}
+
+	savedDays := len(models.Merge(saved, nil).Data)
+	if len(data) < savedDays {
+		return fmt.Errorf("%w: %s holds %d trading days, new data has %d (use -force to overwrite)",
+			ErrHistoryShrunk, c.Path(ticker), savedDays, len(data))
+	}
+	return nil
+}
+
+func (c *CSVStore) Close() error {
+	return nil
+}
@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"webscraper/models"
+
+	_ "modernc.org/sqlite" // pure-Go SQLite driver, registered as "sqlite"
+)
+
+// migrations upgrade the database schema one version at a time. The position
+// in the slice is the version number minus one; applied versions are recorded
+// in schema_migrations, so new migrations must only ever be appended.
+var migrations = []string{
+	// 1: price history keyed by ticker and trading date. Prices are stored
+	// in thousandths like models.Price; dates as ISO YYYY-MM-DD text.
+	`CREATE TABLE prices (
+		ticker       TEXT    NOT NULL,
+		date         TEXT    NOT NULL,
+		open         INTEGER NOT NULL,
+		high         INTEGER NOT NULL,
+		low          INTEGER NOT NULL,
+		close        INTEGER NOT NULL,
+		change       INTEGER NOT NULL,
+		change_pct   REAL    NOT NULL,
+		volume       INTEGER NOT NULL,
+		total_shares INTEGER NOT NULL,
+		trades       INTEGER NOT NULL,
+		updated_at   TEXT    NOT NULL,
+		PRIMARY KEY (ticker, date)
+	) WITHOUT ROWID`,
+}
+
+// SQLiteStore keeps the history of all tickers in one SQLite database.
+// Saving upserts rows, so rows outside the saved range are kept.
+type SQLiteStore struct {
+	path string
+	db   *sql.DB
+}
+
+// OpenSQLite opens or creates the database at path and migrates it to the
+// latest schema version.
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %v", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	// SQLite allows a single writer; serialize access instead of failing with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	store := &SQLiteStore{path: path, db: db}
+	if err := store.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate %s: %v", path, err)
+	}
+	return store, nil
+}
+
+// SchemaVersion returns the latest migration applied to the database.
+func (s *SQLiteStore) SchemaVersion() (int, error) {
+	var version int
+	err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// migrate applies every migration newer than the database's schema version,
+// each in its own transaction.
+func (s *SQLiteStore) migrate() error {
+	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+
+	current, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if current > len(migrations) {
+		return fmt.Errorf("database schema v%d is newer than this build supports (v%d)", current, len(migrations))
+	}
+
+	for version := current + 1; version <= len(migrations); version++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[version-1]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %v", version, err)
+		}
+		_, err = tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`,
+			version, time.Now().UTC().Format(time.RFC3339))
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %v", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %d: %v", version, err)
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Location(ticker string) string {
+	return fmt.Sprintf("%s (ticker %s)", s.path, ticker)
+}
+
+func (s *SQLiteStore) Tickers() ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT ticker FROM prices ORDER BY ticker`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tickers []string
+	for rows.Next() {
+		var ticker string
+		if err := rows.Scan(&ticker); err != nil {
+			return nil, err
+		}
+		tickers = append(tickers, ticker)
+	}
+	return tickers, rows.Err()
+}
+
+func (s *SQLiteStore) Load(ticker string) ([]models.StockData, error) {
+	rows, err := s.db.Query(`
+		SELECT date, open, high, low, close, change, change_pct, volume, total_shares, trades
+		FROM prices WHERE ticker = ? ORDER BY date DESC`, ticker)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []models.StockData
+	for rows.Next() {
+		var (
+			date   string
+			record models.StockData
+		)
+		err := rows.Scan(&date, &record.OpenPrice, &record.HighPrice, &record.LowPrice, &record.ClosePrice,
+			&record.Change, &record.ChangePerc, &record.Volume, &record.TotalShares, &record.NumTrades)
+		if err != nil {
+			return nil, err
+		}
+		record.Date, err = time.Parse(models.DateLayout, date)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid date %q: %v", ticker, date, err)
+		}
+		data = append(data, record)
+	}
+	return data, rows.Err()
+}
+
+func (s *SQLiteStore) Save(ticker string, data []models.StockData) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO prices (ticker, date, open, high, low, close, change, change_pct, volume, total_shares, trades, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (ticker, date) DO UPDATE SET
+			open = excluded.open,
+			high = excluded.high,
+			low = excluded.low,
+			close = excluded.close,
+			change = excluded.change,
+			change_pct = excluded.change_pct,
+			volume = excluded.volume,
+			total_shares = excluded.total_shares,
+			trades = excluded.trades,
+			updated_at = excluded.updated_at`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, record := range data {
+		_, err := stmt.Exec(ticker, record.Date.Format(models.DateLayout),
+			int64(record.OpenPrice), int64(record.HighPrice), int64(record.LowPrice), int64(record.ClosePrice),
+			int64(record.Change), record.ChangePerc, record.Volume, record.TotalShares, record.NumTrades, now)
+		if err != nil {
+			return fmt.Errorf("failed to save %s row for %s: %v", ticker, record.Date.Format(models.DateLayout), err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,73 @@
+// Package storage persists scraped price history. The scraper loads a
+// ticker's saved history before scraping and saves the merged result after,
+// through the Store interface; where the history lives is chosen with a store
+// spec such as "csv:output" or "sqlite:data/prices.db".
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"webscraper/internal/utils"
+	"webscraper/models"
+)
+
+// DefaultSpec is the store used when none is configured: one CSV file per
+// ticker in the output directory.
+const DefaultSpec = "csv:output"
+
+// Options control how stores write history.
+type Options struct {
+	// Backups is the number of previous versions of a file-based ticker
+	// history kept as <file>.bak.N.
+	Backups int
+	// Force allows replacing saved history with fewer rows than it holds.
+	Force bool
+	// CSVSchema is the models CSV schema version CSV files are written in;
+	// zero writes models.SchemaVersion.
+	CSVSchema int
+}
+
+// DefaultOptions returns the options used when none are configured.
+func DefaultOptions() Options {
+	return Options{Backups: 3}
+}
+
+// Store loads and saves the price history of individual tickers.
+type Store interface {
+	// Load returns the saved history of ticker, newest first. A ticker
+	// without saved history yields no records and no error.
+	Load(ticker string) ([]models.StockData, error)
+	// Save replaces or updates the saved history of ticker with data.
+	Save(ticker string, data []models.StockData) error
+	// Tickers returns the tickers with saved history, sorted.
+	Tickers() ([]string, error)
+	// Location describes where ticker's history is saved, for log messages.
+	Location(ticker string) string
+	// Close releases any resources held by the store.
+	Close() error
+}
+
+// Open creates the store described by spec, which has the form
+// "<kind>:<path>". Supported kinds are "csv", whose path is a directory, and
+// "sqlite", whose path is a database file. An empty spec opens DefaultSpec.
+func Open(spec string, options Options, logger *utils.Logger) (Store, error) {
+	if spec == "" {
+		spec = DefaultSpec
+	}
+
+	kind, path, ok := strings.Cut(spec, ":")
+	if !ok || path == "" {
+		return nil, fmt.Errorf("invalid store %q, expected <kind>:<path>", spec)
+	}
+
+	switch kind {
+	case "csv":
+		if options.CSVSchema != 0 && options.CSVSchema != models.SchemaV1 && options.CSVSchema != models.SchemaV2 {
+			return nil, fmt.Errorf("unknown CSV schema version %d (want %d or %d)", options.CSVSchema, models.SchemaV1, models.SchemaV2)
+		}
+		return NewCSVStore(path, options, logger), nil
+	case "sqlite":
+		return OpenSQLite(path)
+	}
+	return nil, fmt.Errorf("unknown store kind %q in %q", kind, spec)
+}
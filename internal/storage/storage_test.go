@@ -0,0 +1,276 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+	"webscraper/models"
+)
+
+// record returns a trading day of December 2024 closing at close.
+func record(day int, close models.Price) models.StockData {
+	return models.StockData{
+		Date:        time.Date(2024, 12, day, 0, 0, 0, 0, time.UTC),
+		OpenPrice:   close - 10,
+		HighPrice:   close + 20,
+		LowPrice:    close - 20,
+		ClosePrice:  close,
+		Change:      -30,
+		ChangePerc:  -0.71,
+		Volume:      236151248,
+		TotalShares: 55827016,
+		NumTrades:   120,
+	}
+}
+
+// stores returns the spec of each kind of store, in a fresh directory.
+func stores(t *testing.T) map[string]string {
+	dir := t.TempDir()
+	return map[string]string{
+		"csv":    "csv:" + filepath.Join(dir, "output"),
+		"sqlite": "sqlite:" + filepath.Join(dir, "data", "prices.db"),
+	}
+}
+
+func openStore(t *testing.T, spec string, options Options) Store {
+	t.Helper()
+	store, err := Open(spec, options, nil)
+	if err != nil {
+		t.Fatalf("Open(%q) failed: %v", spec, err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func load(t *testing.T, store Store, ticker string) []models.StockData {
+	t.Helper()
+	data, err := store.Load(ticker)
+	if err != nil {
+		t.Fatalf("Load(%s) failed: %v", ticker, err)
+	}
+	return data
+}
+
+func TestStoreSaveLoad(t *testing.T) {
+	for kind, spec := range stores(t) {
+		store := openStore(t, spec, DefaultOptions())
+
+		if data := load(t, store, "BBOB"); len(data) != 0 {
+			t.Errorf("%s: got %d rows for a ticker never saved", kind, len(data))
+		}
+
+		data := []models.StockData{record(23, 4230), record(22, 4200), record(19, 4150)}
+		if err := store.Save("BBOB", data); err != nil {
+			t.Fatalf("%s: Save failed: %v", kind, err)
+		}
+		if err := store.Save("IBSD", []models.StockData{record(23, 52000)}); err != nil {
+			t.Fatalf("%s: Save failed: %v", kind, err)
+		}
+
+		if got := load(t, store, "BBOB"); !reflect.DeepEqual(got, data) {
+			t.Errorf("%s: loaded\n%+v\nwant\n%+v", kind, got, data)
+		}
+		tickers, err := store.Tickers()
+		if err != nil {
+			t.Fatalf("%s: Tickers failed: %v", kind, err)
+		}
+		if want := []string{"BBOB", "IBSD"}; !reflect.DeepEqual(tickers, want) {
+			t.Errorf("%s: got tickers %v, want %v", kind, tickers, want)
+		}
+	}
+}
+
+func TestStoreSaveUpdatesByDate(t *testing.T) {
+	for kind, spec := range stores(t) {
+		store := openStore(t, spec, DefaultOptions())
+		if err := store.Save("BBOB", []models.StockData{record(22, 4200), record(19, 4150)}); err != nil {
+			t.Fatalf("%s: Save failed: %v", kind, err)
+		}
+
+		// A new day and a revised one, merged the way the scraper does
+		merged := models.Merge(load(t, store, "BBOB"), []models.StockData{record(23, 4230), record(22, 4210)}).Data
+		if err := store.Save("BBOB", merged); err != nil {
+			t.Fatalf("%s: Save failed: %v", kind, err)
+		}
+
+		want := []models.StockData{record(23, 4230), record(22, 4210), record(19, 4150)}
+		if got := load(t, store, "BBOB"); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: loaded\n%+v\nwant\n%+v", kind, got, want)
+		}
+	}
+}
+
+func TestStoreSaveFewerDays(t *testing.T) {
+	saved := []models.StockData{record(23, 4230), record(22, 4200), record(19, 4150)}
+	partial := []models.StockData{record(23, 4240)}
+
+	for kind, spec := range stores(t) {
+		store := openStore(t, spec, DefaultOptions())
+		if err := store.Save("BBOB", saved); err != nil {
+			t.Fatalf("%s: Save failed: %v", kind, err)
+		}
+
+		err := store.Save("BBOB", partial)
+		switch kind {
+		case "csv":
+			// The file is rewritten whole, so fewer days would lose history
+			if !errors.Is(err, ErrHistoryShrunk) {
+				t.Errorf("%s: Save with fewer days returned %v, want %v", kind, err, ErrHistoryShrunk)
+			}
+			if got := load(t, store, "BBOB"); !reflect.DeepEqual(got, saved) {
+				t.Errorf("%s: refused save changed the history to %+v", kind, got)
+			}
+		case "sqlite":
+			// Rows are upserted, so days missing from data are kept
+			if err != nil {
+				t.Fatalf("%s: Save failed: %v", kind, err)
+			}
+			want := []models.StockData{record(23, 4240), record(22, 4200), record(19, 4150)}
+			if got := load(t, store, "BBOB"); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: loaded\n%+v\nwant\n%+v", kind, got, want)
+			}
+		}
+	}
+}
+
+func TestCSVStoreForceShrink(t *testing.T) {
+	dir := t.TempDir()
+	store := NewCSVStore(dir, Options{Force: true}, nil)
+	if err := store.Save("BBOB", []models.StockData{record(23, 4230), record(22, 4200)}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	partial := []models.StockData{record(23, 4240)}
+	if err := store.Save("BBOB", partial); err != nil {
+		t.Fatalf("Save with Force failed: %v", err)
+	}
+	if got := load(t, store, "BBOB"); !reflect.DeepEqual(got, partial) {
+		t.Errorf("loaded %+v, want %+v", got, partial)
+	}
+}
+
+func TestCSVStoreUnreadableBaseline(t *testing.T) {
+	dir := t.TempDir()
+	store := NewCSVStore(dir, DefaultOptions(), nil)
+	if err := os.WriteFile(store.Path("BBOB"), []byte("not,a,history\n1,2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := []models.StockData{record(23, 4230)}
+	if err := store.Save("BBOB", data); err != nil {
+		t.Fatalf("Save over an unreadable file failed: %v", err)
+	}
+	if got := load(t, store, "BBOB"); !reflect.DeepEqual(got, data) {
+		t.Errorf("loaded %+v, want %+v", got, data)
+	}
+}
+
+func TestCSVStoreSchema(t *testing.T) {
+	data := []models.StockData{record(23, 4230)}
+	for _, version := range []int{models.SchemaV1, models.SchemaV2} {
+		dir := t.TempDir()
+		store := openStore(t, "csv:"+dir, Options{CSVSchema: version})
+		if err := store.Save("BBOB", data); err != nil {
+			t.Fatalf("v%d: Save failed: %v", version, err)
+		}
+		file, err := os.Open(filepath.Join(dir, "BBOB_data.csv"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, got, err := models.ReadCSV(file)
+		file.Close()
+		if err != nil || got != version {
+			t.Errorf("v%d: file read as schema v%d (%v)", version, got, err)
+		}
+	}
+	if _, err := Open("csv:"+t.TempDir(), Options{CSVSchema: 3}, nil); err == nil {
+		t.Error("Open accepted CSV schema version 3")
+	}
+}
+
+func TestSQLiteMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.db")
+
+	store, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite failed: %v", err)
+	}
+	if version, err := store.SchemaVersion(); err != nil || version != len(migrations) {
+		t.Errorf("new database at schema v%d (%v), want v%d", version, err, len(migrations))
+	}
+	data := []models.StockData{record(23, 4230)}
+	if err := store.Save("BBOB", data); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	store.Close()
+
+	// Reopening an up-to-date database applies nothing and keeps the rows
+	store, err = OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("reopening failed: %v", err)
+	}
+	if got := load(t, store, "BBOB"); !reflect.DeepEqual(got, data) {
+		t.Errorf("loaded %+v after reopening, want %+v", got, data)
+	}
+	var applied int
+	if err := store.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil || applied != len(migrations) {
+		t.Errorf("got %d applied migrations (%v), want %d", applied, err, len(migrations))
+	}
+	store.Close()
+
+	// A database written by a newer build is refused
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, len(migrations)+1, "2030-01-01T00:00:00Z")
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store, err := OpenSQLite(path); err == nil {
+		store.Close()
+		t.Error("OpenSQLite accepted a newer schema version")
+	}
+}
+
+func TestSQLiteMigratesExistingDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.db")
+
+	// A database created outside the store, without any migrations applied
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`CREATE TABLE notes (text TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	store, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite failed: %v", err)
+	}
+	defer store.Close()
+	if version, err := store.SchemaVersion(); err != nil || version != len(migrations) {
+		t.Errorf("migrated database at schema v%d (%v), want v%d", version, err, len(migrations))
+	}
+	if err := store.Save("BBOB", []models.StockData{record(23, 4230)}); err != nil {
+		t.Errorf("Save after migrating failed: %v", err)
+	}
+	var notes int
+	if err := store.db.QueryRow(`SELECT COUNT(*) FROM notes`).Scan(&notes); err != nil {
+		t.Errorf("existing table lost in migration: %v", err)
+	}
+}
+
+func TestOpenInvalidSpec(t *testing.T) {
+	for _, spec := range []string{"csv", "csv:", "mysql:prices", ":output"} {
+		if store, err := Open(spec, DefaultOptions(), nil); err == nil {
+			store.Close()
+			t.Errorf("Open(%q) succeeded, want an error", spec)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// WriteFileAtomic replaces path with the output of write without ever leaving
+// a partially written file behind: the data goes to a temporary file in the
+// same directory, which is synced and then renamed over path. Before the
+// rename, up to backups previous versions are kept as path.bak.1 (newest)
+// through path.bak.<backups>. The new file keeps the permissions of the one
+// it replaces, or gets 0644 if path did not exist.
+func WriteFileAtomic(path string, backups int, write func(w io.Writer) error) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	tmpName := tmp.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmpName)
+		}
+	}()
+
+	if err := write(tmp); err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	// CreateTemp makes the file owner-only
+	if err := tmp.Chmod(mode); err != nil {
+		return fmt.Errorf("failed to set permissions of %s: %v", tmpName, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %v", tmpName, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %v", tmpName, err)
+	}
+
+	if backups > 0 {
+		if err := rotateBackups(path, backups); err != nil {
+			return fmt.Errorf("failed to back up %s: %v", path, err)
+		}
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %v", path, err)
+	}
+	committed = true
+
+	// Persist the rename itself; directories cannot be synced on Windows
+	if runtime.GOOS != "windows" {
+		if d, err := os.Open(dir); err == nil {
+			d.Sync()
+			d.Close()
+		}
+	}
+	return nil
+}
+
+// rotateBackups shifts path.bak.N up by one, dropping the oldest, and keeps
+// the current contents of path as path.bak.1. path itself stays in place, so
+// a crash during rotation never leaves it missing.
+func rotateBackups(path string, backups int) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	for n := backups - 1; n >= 1; n-- {
+		from := backupName(path, n)
+		if _, err := os.Stat(from); err != nil {
+			continue
+		}
+		if err := os.Rename(from, backupName(path, n+1)); err != nil {
+			return err
+		}
+	}
+
+	newest := backupName(path, 1)
+	os.Remove(newest)
+	// A hard link is instant; fall back to copying where links are unsupported
+	if err := os.Link(path, newest); err == nil {
+		return nil
+	}
+	return copyFile(path, newest)
+}
+
+func backupName(path string, n int) string {
+	return fmt.Sprintf("%s.bak.%d", path, n)
+}
+
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeString returns a WriteFileAtomic callback writing s.
+func writeString(s string) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return string(content)
+}
+
+// leftovers returns the temporary files WriteFileAtomic left in dir.
+func leftovers(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+func TestWriteFileAtomicBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "BBOB_data.csv")
+
+	const backups = 3
+	for i := 1; i <= 5; i++ {
+		if err := WriteFileAtomic(path, backups, writeString(fmt.Sprintf("version %d", i))); err != nil {
+			t.Fatalf("write %d failed: %v", i, err)
+		}
+	}
+
+	if got := readFile(t, path); got != "version 5" {
+		t.Errorf("file holds %q, want version 5", got)
+	}
+	// bak.1 is the newest previous version
+	for n := 1; n <= backups; n++ {
+		if got, want := readFile(t, backupName(path, n)), fmt.Sprintf("version %d", 5-n); got != want {
+			t.Errorf("%s holds %q, want %q", backupName(path, n), got, want)
+		}
+	}
+	if _, err := os.Stat(backupName(path, backups+1)); !os.IsNotExist(err) {
+		t.Errorf("%s kept beyond %d backups", backupName(path, backups+1), backups)
+	}
+	if names := leftovers(t, dir); len(names) > 0 {
+		t.Errorf("temporary files left behind: %v", names)
+	}
+}
+
+func TestWriteFileAtomicFewBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.csv")
+
+	// The first write has nothing to back up, the second only one version
+	for i := 1; i <= 2; i++ {
+		if err := WriteFileAtomic(path, 3, writeString(fmt.Sprintf("version %d", i))); err != nil {
+			t.Fatalf("write %d failed: %v", i, err)
+		}
+	}
+	if got := readFile(t, backupName(path, 1)); got != "version 1" {
+		t.Errorf("bak.1 holds %q, want version 1", got)
+	}
+	if _, err := os.Stat(backupName(path, 2)); !os.IsNotExist(err) {
+		t.Error("bak.2 created without a second previous version")
+	}
+
+	// No backups at all
+	other := filepath.Join(dir, "other.csv")
+	for i := 1; i <= 2; i++ {
+		if err := WriteFileAtomic(other, 0, writeString("x")); err != nil {
+			t.Fatalf("write %d failed: %v", i, err)
+		}
+	}
+	if _, err := os.Stat(backupName(other, 1)); !os.IsNotExist(err) {
+		t.Error("backup kept with backups set to 0")
+	}
+}
+
+func TestWriteFileAtomicFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.csv")
+	if err := WriteFileAtomic(path, 2, writeString("saved")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	errWrite := errors.New("scrape broke")
+	err := WriteFileAtomic(path, 2, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return errWrite
+	})
+	if !errors.Is(err, errWrite) {
+		t.Fatalf("got error %v, want %v", err, errWrite)
+	}
+
+	if got := readFile(t, path); got != "saved" {
+		t.Errorf("file holds %q after a failed write, want the saved version", got)
+	}
+	if _, err := os.Stat(backupName(path, 1)); !os.IsNotExist(err) {
+		t.Error("a failed write rotated the backups")
+	}
+	if names := leftovers(t, dir); len(names) > 0 {
+		t.Errorf("temporary files left behind: %v", names)
+	}
+}
+
+func TestWriteFileAtomicPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permissions do not apply on Windows")
+	}
+	dir := t.TempDir()
+
+	created := filepath.Join(dir, "new.csv")
+	if err := WriteFileAtomic(created, 0, writeString("x")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	info, err := os.Stat(created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("new file has mode %v, want 0644", info.Mode().Perm())
+	}
+
+	existing := filepath.Join(dir, "existing.csv")
+	if err := os.WriteFile(existing, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(existing, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(existing, 1, writeString("new")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	info, err = os.Stat(existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("replaced file has mode %v, want 0640", info.Mode().Perm())
+	}
+}
@@ -1,36 +1,180 @@
-package utils
-
-import (
-	"os"
-
-	"gopkg.in/yaml.v2"
-)
-
-type Config struct {
-	Scraper struct {
-		Timeout  int `yaml:"timeout"`
-		Retries  int `yaml:"retries"`
-		Delay    int `yaml:"delay"`
-		MaxPages int `yaml:"maxPages"`
-		Browser  struct {
-			Headless bool `yaml:"headless"`
-			Debug    bool `yaml:"debug"`
-		} `yaml:"browser"`
-	} `yaml:"scraper"`
-}
-
-func LoadConfig(path string) (*Config, error) {
-	config := &Config{}
-
-	file, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-
-	err = yaml.Unmarshal(file, config)
-	if err != nil {
-		return nil, err
-	}
-
-	return config, nil
-}
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DateLayout is the layout used for dates in the config file and CLI flags.
+const DateLayout = "2006-01-02"
+
+// DefaultFromDate is the first trading date fetched when none is configured.
+const DefaultFromDate = "2020-01-01"
+
+type Config struct {
+	Scraper struct {
+		Timeout    int      `yaml:"timeout"`
+		Retries    int      `yaml:"retries"`
+		MaxPages   int      `yaml:"maxPages"`
+		FromDate   string   `yaml:"fromDate"`
+		ToDate     string   `yaml:"toDate"`
+		BaseURL    string   `yaml:"baseURL"`
+		Workers    int      `yaml:"workers"`
+		RateLimit  float64  `yaml:"rateLimit"`
+		RunTimeout Duration `yaml:"runTimeout"`
+		StateFile  string   `yaml:"stateFile"`
+		// TickersFile lists the known tickers, used by list, daemon, serve and
+		// discover, and by scrape -all when discovery fails
+		TickersFile string `yaml:"tickersFile"`
+		Waits       struct {
+			BetweenTickers Duration `yaml:"betweenTickers"`
+			AfterError     Duration `yaml:"afterError"`
+			AfterRefresh   Duration `yaml:"afterRefresh"`
+			TableLoad      Duration `yaml:"tableLoad"`
+			BrowserClose   Duration `yaml:"browserClose"`
+		} `yaml:"waits"`
+		Browser struct {
+			Headless bool `yaml:"headless"`
+			Debug    bool `yaml:"debug"`
+		} `yaml:"browser"`
+	} `yaml:"scraper"`
+	Storage struct {
+		Store     string `yaml:"store"`     // csv:<dir> or sqlite:<file>
+		Backups   int    `yaml:"backups"`   // previous CSV versions kept as .bak.N
+		CSVSchema int    `yaml:"csvSchema"` // CSV layout written: 1 (legacy) or 2 (ISO dates, snake_case)
+	} `yaml:"storage"`
+	Report struct {
+		Path        string `yaml:"path"`
+		MaxFailures int    `yaml:"maxFailures"` // -1 never fails the run
+	} `yaml:"report"`
+	Export struct {
+		Dir     string   `yaml:"dir"`
+		Formats []string `yaml:"formats"` // csv, jsonl, parquet
+	} `yaml:"export"`
+	Logging LoggingConfig `yaml:"logging"`
+	Daemon  struct {
+		Schedule   string   `yaml:"schedule"`   // cron spec: minute hour day-of-month month day-of-week
+		Timezone   string   `yaml:"timezone"`   // time zone the schedule and holidays are in
+		Weekend    []string `yaml:"weekend"`    // weekdays without trading
+		Holidays   []string `yaml:"holidays"`   // YYYY-MM-DD dates without trading
+		RunOnStart bool     `yaml:"runOnStart"` // run once immediately when the daemon starts
+		StateFile  string   `yaml:"stateFile"`  // run state of scheduled and API batches, kept apart from scraper.stateFile
+	} `yaml:"daemon"`
+	API struct {
+		Addr      string `yaml:"addr"`      // listen address used by serve and daemon -serve
+		MaxQueued int    `yaml:"maxQueued"` // scrape jobs that may wait before requests are rejected
+	} `yaml:"api"`
+	Metrics struct {
+		Addr string `yaml:"addr"` // listen address of the /metrics endpoint, empty disables it
+	} `yaml:"metrics"`
+}
+
+// Duration is a time.Duration read from YAML as a Go duration string such as
+// "1.5s" or "500ms". Bare numbers are read as seconds for older configs.
+type Duration time.Duration
+
+// D returns the value as a time.Duration.
+func (d Duration) D() time.Duration {
+	return time.Duration(d)
+}
+
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %v", value, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// defaultConfig returns the values used for settings missing from the file.
+func defaultConfig() *Config {
+	config := &Config{}
+	config.Scraper.StateFile = "output/run_state.json"
+	config.Scraper.TickersFile = "TICKERS.csv"
+	config.Scraper.Waits.BetweenTickers = Duration(10 * time.Second)
+	config.Scraper.Waits.AfterError = Duration(10 * time.Second)
+	config.Scraper.Waits.AfterRefresh = Duration(1 * time.Second)
+	config.Scraper.Waits.TableLoad = Duration(15 * time.Second)
+	config.Scraper.Waits.BrowserClose = Duration(2 * time.Second)
+	config.Storage.Backups = 3
+	config.Storage.CSVSchema = 1
+	config.Report.Path = "output/run_report.json"
+	config.Export.Dir = "output"
+	config.Logging.Level = "info"
+	config.Logging.Format = "text"
+	config.Logging.Dir = DefaultLogDir
+	config.Logging.MaxSizeMB = 10
+	config.Logging.MaxAgeDays = 30
+	config.Logging.MaxFiles = 10
+	config.Logging.Compress = true
+	config.Daemon.Schedule = "30 13 * * *"
+	config.Daemon.Timezone = "Asia/Baghdad"
+	config.Daemon.Weekend = []string{"Friday", "Saturday"}
+	config.Daemon.StateFile = "output/daemon_state.json"
+	config.API.Addr = "127.0.0.1:8080"
+	config.API.MaxQueued = 20
+	return config
+}
+
+func LoadConfig(path string) (*Config, error) {
+	config := defaultConfig()
+
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(file, config)
+	if err != nil {
+		return nil, err
+	}
+	if config.Logging.Dir == "" {
+		config.Logging.Dir = DefaultLogDir
+	}
+
+	return config, nil
+}
+
+// ResolveDateRange parses the configured date range. An empty from falls back
+// to DefaultFromDate and an empty to means today. It returns an error when
+// either date is malformed or from is after to.
+func ResolveDateRange(from, to string) (time.Time, time.Time, error) {
+	if from == "" {
+		from = DefaultFromDate
+	}
+	fromDate, err := time.Parse(DateLayout, from)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid from date %q (want YYYY-MM-DD): %v", from, err)
+	}
+
+	now := time.Now()
+	toDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if to != "" {
+		toDate, err = time.Parse(DateLayout, to)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to date %q (want YYYY-MM-DD): %v", to, err)
+		}
+	}
+
+	if fromDate.After(toDate) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from date %s is after to date %s",
+			fromDate.Format(DateLayout), toDate.Format(DateLayout))
+	}
+
+	return fromDate, toDate, nil
+}
@@ -1,73 +1,204 @@
-package utils
-
-import (
-	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
-	"time"
-)
-
-type Logger struct {
-	file *os.File
-}
-
-func NewLogger() (*Logger, error) {
-	// Create logs directory if it doesn't exist
-	err := os.MkdirAll("logs", 0755)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create logs directory: %v", err)
-	}
-
-	// Create log file with timestamp
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	filename := filepath.Join("logs", fmt.Sprintf("scraper_%s.log", timestamp))
-
-	file, err := os.Create(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create log file: %v", err)
-	}
-
-	return &Logger{file: file}, nil
-}
-
-func (l *Logger) Info(format string, args ...interface{}) {
-	l.log("INFO", format, args...)
-}
-
-func (l *Logger) Debug(format string, args ...interface{}) {
-	// Skip cookie-related error messages
-	if strings.Contains(format, "could not unmarshal event") &&
-		strings.Contains(format, "cookiePart") {
-		return
-	}
-	l.log("DEBUG", format, args...)
-}
-
-func (l *Logger) Error(format string, args ...interface{}) {
-	l.log("ERROR", format, args...)
-}
-
-func (l *Logger) Fatal(format string, args ...interface{}) {
-	l.log("FATAL", format, args...)
-	os.Exit(1)
-}
-
-func (l *Logger) log(level string, format string, args ...interface{}) {
-	timestamp := time.Now().Format("2006/01/02 15:04:05")
-	message := fmt.Sprintf(format, args...)
-	logLine := fmt.Sprintf("%s: %s %s\n", level, timestamp, message)
-
-	// Write to file
-	fmt.Fprint(l.file, logLine)
-
-	// Also print to console
-	fmt.Print(logLine)
-}
-
-func (l *Logger) Close() error {
-	if l.file != nil {
-		return l.file.Close()
-	}
-	return nil
-}
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// LevelFatal is the level of messages logged by Logger.Fatal.
+const LevelFatal = slog.Level(12)
+
+// DefaultLogDir is the log directory used when none is configured.
+const DefaultLogDir = "logs"
+
+// LogFileName is the name of the active log file; rotated files are named
+// scraper-<timestamp>.log and compressed as .log.gz.
+const LogFileName = "scraper.log"
+
+// LoggingConfig selects what the logger writes, how, and how long log files
+// are kept.
+type LoggingConfig struct {
+	Level      string `yaml:"level"`      // debug, info, warn or error
+	Format     string `yaml:"format"`     // text or json
+	Dir        string `yaml:"dir"`        // directory holding the log files
+	MaxSizeMB  int    `yaml:"maxSizeMB"`  // rotate the log file once it reaches this size
+	MaxAgeDays int    `yaml:"maxAgeDays"` // delete rotated files older than this, 0 keeps them
+	MaxFiles   int    `yaml:"maxFiles"`   // rotated files to keep, 0 keeps all
+	Compress   bool   `yaml:"compress"`   // gzip rotated files
+
+	// Console receives the log records besides the log file; nil means
+	// stdout. Commands that print results on stdout log to stderr instead.
+	Console io.Writer `yaml:"-"`
+}
+
+// Logger writes leveled, structured log records to the console and to a
+// rotating log file. Messages are printf-style; attributes added with With,
+// such as the ticker or page being scraped, are attached to every record.
+type Logger struct {
+	slog *slog.Logger
+	file io.Closer
+}
+
+func NewLogger(config LoggingConfig) (*Logger, error) {
+	level, err := ParseLevel(config.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := config.Dir
+	if dir == "" {
+		dir = DefaultLogDir
+	}
+
+	// Create logs directory if it doesn't exist
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create logs directory: %v", err)
+	}
+	removeOldRunLogs(dir, config.MaxAgeDays, config.MaxFiles)
+
+	file := &lumberjack.Logger{
+		Filename:   filepath.Join(dir, LogFileName),
+		MaxSize:    config.MaxSizeMB,
+		MaxAge:     config.MaxAgeDays,
+		MaxBackups: config.MaxFiles,
+		Compress:   config.Compress,
+		LocalTime:  true,
+	}
+
+	console := config.Console
+	if console == nil {
+		console = os.Stdout
+	}
+	handler, err := newHandler(io.MultiWriter(console, file), config.Format, level)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &Logger{slog: slog.New(handler), file: file}, nil
+}
+
+// removeOldRunLogs applies the retention settings to the per-run
+// scraper_<timestamp>.log files written by earlier versions, which the
+// rotating log file does not manage.
+func removeOldRunLogs(dir string, maxAgeDays, maxFiles int) {
+	matches, err := filepath.Glob(filepath.Join(dir, "scraper_*.log"))
+	if err != nil || len(matches) == 0 {
+		return
+	}
+
+	// Timestamped names sort oldest first
+	sort.Strings(matches)
+	cutoff := time.Now().AddDate(0, 0, -maxAgeDays)
+	for i, path := range matches {
+		keep := len(matches) - i
+		tooMany := maxFiles > 0 && keep > maxFiles
+		tooOld := false
+		if maxAgeDays > 0 {
+			if info, err := os.Stat(path); err == nil && info.ModTime().Before(cutoff) {
+				tooOld = true
+			}
+		}
+		if tooMany || tooOld {
+			os.Remove(path)
+		}
+	}
+}
+
+// ParseLevel converts a configured level name to a slog level. An empty name
+// selects info.
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", name)
+}
+
+// newHandler creates the slog handler for the configured format.
+func newHandler(w io.Writer, format string, level slog.Level) (slog.Handler, error) {
+	options := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: replaceLevel,
+	}
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "text":
+		return slog.NewTextHandler(w, options), nil
+	case "json":
+		return slog.NewJSONHandler(w, options), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q (want text or json)", format)
+}
+
+// replaceLevel names LevelFatal, which slog would print as "ERROR+4".
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if level, ok := a.Value.Any().(slog.Level); ok && level == LevelFatal {
+			a.Value = slog.StringValue("FATAL")
+		}
+	}
+	return a
+}
+
+// With returns a logger that adds the given key-value pairs to every record,
+// e.g. logger.With("ticker", "BBOB"). It shares the log file with l.
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{slog: l.slog.With(args...), file: l.file}
+}
+
+func (l *Logger) Info(format string, args ...interface{}) {
+	l.log(slog.LevelInfo, format, args...)
+}
+
+func (l *Logger) Debug(format string, args ...interface{}) {
+	// Skip cookie-related error messages
+	if strings.Contains(format, "could not unmarshal event") &&
+		strings.Contains(format, "cookiePart") {
+		return
+	}
+	l.log(slog.LevelDebug, format, args...)
+}
+
+func (l *Logger) Warn(format string, args ...interface{}) {
+	l.log(slog.LevelWarn, format, args...)
+}
+
+func (l *Logger) Error(format string, args ...interface{}) {
+	l.log(slog.LevelError, format, args...)
+}
+
+func (l *Logger) Fatal(format string, args ...interface{}) {
+	l.log(LevelFatal, format, args...)
+	os.Exit(1)
+}
+
+func (l *Logger) log(level slog.Level, format string, args ...interface{}) {
+	ctx := context.Background()
+	// Skip formatting messages that would be filtered out anyway
+	if !l.slog.Enabled(ctx, level) {
+		return
+	}
+	l.slog.Log(ctx, level, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Close() error {
+	if l.file != nil {
+		return l.file.Close()
+	}
+	return nil
+}
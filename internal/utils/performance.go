@@ -1,184 +1,238 @@
-package utils
-
-import (
-	"fmt"
-	"sort"
-	"strings"
-	"sync"
-	"time"
-)
-
-// StepTiming holds timing information for a single step
-type StepTiming struct {
-	Name      string
-	StartTime time.Time
-	Duration  time.Duration
-	SubSteps  []*StepTiming
-}
-
-// StepAggregate holds aggregate timing information for a step
-type StepAggregate struct {
-	Count    int
-	Total    time.Duration
-	Average  time.Duration
-	Min      time.Duration
-	Max      time.Duration
-	StepName string
-}
-
-// PerformanceTracker tracks execution times of different steps
-type PerformanceTracker struct {
-	currentStep *StepTiming
-	steps       []*StepTiming
-	aggregates  map[string]*StepAggregate
-	mu          sync.Mutex
-}
-
-func NewPerformanceTracker() *PerformanceTracker {
-	return &PerformanceTracker{
-		steps:      make([]*StepTiming, 0),
-		aggregates: make(map[string]*StepAggregate),
-	}
-}
-
-// StartStep begins timing a new step
-func (pt *PerformanceTracker) StartStep(name string) {
-	pt.mu.Lock()
-	defer pt.mu.Unlock()
-
-	step := &StepTiming{
-		Name:      name,
-		StartTime: time.Now(),
-	}
-
-	if pt.currentStep != nil {
-		pt.currentStep.SubSteps = append(pt.currentStep.SubSteps, step)
-	} else {
-		pt.steps = append(pt.steps, step)
-	}
-	pt.currentStep = step
-}
-
-// EndStep completes timing for the current step
-func (pt *PerformanceTracker) EndStep() {
-	pt.mu.Lock()
-	defer pt.mu.Unlock()
-
-	if pt.currentStep != nil {
-		pt.currentStep.Duration = time.Since(pt.currentStep.StartTime)
-		pt.updateAggregates(pt.currentStep)
-
-		// Move back to parent step if exists
-		found := false
-		for _, step := range pt.steps {
-			if found = pt.findParentStep(step, pt.currentStep); found {
-				break
-			}
-		}
-		if !found {
-			pt.currentStep = nil
-		}
-	}
-}
-
-// findParentStep recursively finds the parent of a step
-func (pt *PerformanceTracker) findParentStep(current *StepTiming, target *StepTiming) bool {
-	for _, subStep := range current.SubSteps {
-		if subStep == target {
-			pt.currentStep = current
-			return true
-		}
-		if pt.findParentStep(subStep, target) {
-			return true
-		}
-	}
-	return false
-}
-
-// GenerateReport creates a formatted performance report
-func (pt *PerformanceTracker) GenerateReport() string {
-	var sb strings.Builder
-	sb.WriteString("\n=== Performance Report ===\n")
-
-	for _, step := range pt.steps {
-		pt.writeStepReport(&sb, step, 0)
-	}
-
-	return sb.String()
-}
-
-func (pt *PerformanceTracker) writeStepReport(sb *strings.Builder, step *StepTiming, level int) {
-	indent := strings.Repeat("  ", level)
-	sb.WriteString(fmt.Sprintf("%s%s: %v\n", indent, step.Name, step.Duration.Round(time.Millisecond)))
-
-	for _, subStep := range step.SubSteps {
-		pt.writeStepReport(sb, subStep, level+1)
-	}
-}
-
-// updateAggregates updates aggregate timing information for a step
-func (pt *PerformanceTracker) updateAggregates(step *StepTiming) {
-	pt.mu.Lock()
-	defer pt.mu.Unlock()
-
-	agg, exists := pt.aggregates[step.Name]
-	if !exists {
-		agg = &StepAggregate{
-			StepName: step.Name,
-			Min:      step.Duration,
-			Max:      step.Duration,
-		}
-		pt.aggregates[step.Name] = agg
-	}
-
-	agg.Count++
-	agg.Total += step.Duration
-	agg.Average = agg.Total / time.Duration(agg.Count)
-
-	if step.Duration < agg.Min {
-		agg.Min = step.Duration
-	}
-	if step.Duration > agg.Max {
-		agg.Max = step.Duration
-	}
-
-	// Recursively update aggregates for substeps
-	for _, subStep := range step.SubSteps {
-		pt.updateAggregates(subStep)
-	}
-}
-
-// GenerateAggregateReport generates an aggregate performance report
-func (pt *PerformanceTracker) GenerateAggregateReport() string {
-	var sb strings.Builder
-	sb.WriteString("\n=== Aggregate Performance Report ===\n")
-
-	// Sort steps by total time
-	var steps []*StepAggregate
-	for _, agg := range pt.aggregates {
-		steps = append(steps, agg)
-	}
-	sort.Slice(steps, func(i, j int) bool {
-		return steps[i].Total > steps[j].Total
-	})
-
-	// Write sorted aggregates
-	for _, agg := range steps {
-		sb.WriteString(fmt.Sprintf(
-			"Step: %s\n"+
-				"  Count:   %d\n"+
-				"  Total:   %v\n"+
-				"  Average: %v\n"+
-				"  Min:     %v\n"+
-				"  Max:     %v\n",
-			agg.StepName,
-			agg.Count,
-			agg.Total.Round(time.Millisecond),
-			agg.Average.Round(time.Millisecond),
-			agg.Min.Round(time.Millisecond),
-			agg.Max.Round(time.Millisecond),
-		))
-	}
-
-	return sb.String()
-}
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StepTiming holds timing information for a single step
+type StepTiming struct {
+	Name      string
+	StartTime time.Time
+	Duration  time.Duration // zero until the step ends
+	Ended     bool
+	SubSteps  []*StepTiming
+}
+
+// StepAggregate holds aggregate timing information for a step
+type StepAggregate struct {
+	Count    int
+	Total    time.Duration
+	Average  time.Duration
+	Min      time.Duration
+	Max      time.Duration
+	StepName string
+}
+
+// PerformanceTracker tracks execution times of different steps. Steps are
+// timed with spans: Start returns a span for a top-level step, Span.Start
+// one for a sub-step, and Span.End stops the clock. Spans carry their own
+// position in the step tree, so one tracker can be shared by concurrent
+// goroutines.
+type PerformanceTracker struct {
+	mu         sync.Mutex
+	steps      []*StepTiming
+	aggregates map[string]*StepAggregate
+}
+
+// Span is a step being timed. It must be ended exactly once; further calls to
+// End are ignored.
+type Span struct {
+	pt     *PerformanceTracker
+	timing *StepTiming
+}
+
+func NewPerformanceTracker() *PerformanceTracker {
+	return &PerformanceTracker{
+		steps:      make([]*StepTiming, 0),
+		aggregates: make(map[string]*StepAggregate),
+	}
+}
+
+// Start begins timing a new top-level step.
+func (pt *PerformanceTracker) Start(name string) *Span {
+	step := &StepTiming{Name: name, StartTime: time.Now()}
+
+	pt.mu.Lock()
+	pt.steps = append(pt.steps, step)
+	pt.mu.Unlock()
+
+	return &Span{pt: pt, timing: step}
+}
+
+// Start begins timing a sub-step of the span's step.
+func (sp *Span) Start(name string) *Span {
+	step := &StepTiming{Name: name, StartTime: time.Now()}
+
+	sp.pt.mu.Lock()
+	sp.timing.SubSteps = append(sp.timing.SubSteps, step)
+	sp.pt.mu.Unlock()
+
+	return &Span{pt: sp.pt, timing: step}
+}
+
+// End completes the step and returns its duration.
+func (sp *Span) End() time.Duration {
+	now := time.Now()
+
+	sp.pt.mu.Lock()
+	defer sp.pt.mu.Unlock()
+
+	if sp.timing.Ended {
+		return sp.timing.Duration
+	}
+	sp.timing.Duration = now.Sub(sp.timing.StartTime)
+	sp.timing.Ended = true
+	sp.pt.updateAggregates(sp.timing.Name, sp.timing.Duration)
+	return sp.timing.Duration
+}
+
+// GenerateReport creates a formatted performance report
+func (pt *PerformanceTracker) GenerateReport() string {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	var sb strings.Builder
+	sb.WriteString("\n=== Performance Report ===\n")
+
+	for _, step := range pt.steps {
+		pt.writeStepReport(&sb, step, 0)
+	}
+
+	return sb.String()
+}
+
+func (pt *PerformanceTracker) writeStepReport(sb *strings.Builder, step *StepTiming, level int) {
+	indent := strings.Repeat("  ", level)
+	if step.Ended {
+		sb.WriteString(fmt.Sprintf("%s%s: %v\n", indent, step.Name, step.Duration.Round(time.Millisecond)))
+	} else {
+		sb.WriteString(fmt.Sprintf("%s%s: running for %v\n", indent, step.Name, time.Since(step.StartTime).Round(time.Millisecond)))
+	}
+
+	for _, subStep := range step.SubSteps {
+		pt.writeStepReport(sb, subStep, level+1)
+	}
+}
+
+// updateAggregates adds one run of the named step to its aggregate. The
+// caller must hold pt.mu.
+func (pt *PerformanceTracker) updateAggregates(name string, duration time.Duration) {
+	agg, exists := pt.aggregates[name]
+	if !exists {
+		agg = &StepAggregate{
+			StepName: name,
+			Min:      duration,
+			Max:      duration,
+		}
+		pt.aggregates[name] = agg
+	}
+
+	agg.Count++
+	agg.Total += duration
+	agg.Average = agg.Total / time.Duration(agg.Count)
+
+	if duration < agg.Min {
+		agg.Min = duration
+	}
+	if duration > agg.Max {
+		agg.Max = duration
+	}
+}
+
+// Aggregates returns a copy of the aggregate timings, sorted by total time.
+func (pt *PerformanceTracker) Aggregates() []StepAggregate {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	steps := make([]StepAggregate, 0, len(pt.aggregates))
+	for _, agg := range pt.aggregates {
+		steps = append(steps, *agg)
+	}
+	sort.Slice(steps, func(i, j int) bool {
+		return steps[i].Total > steps[j].Total
+	})
+	return steps
+}
+
+// GenerateAggregateReport generates an aggregate performance report
+func (pt *PerformanceTracker) GenerateAggregateReport() string {
+	var sb strings.Builder
+	sb.WriteString("\n=== Aggregate Performance Report ===\n")
+
+	// Write aggregates sorted by total time
+	for _, agg := range pt.Aggregates() {
+		sb.WriteString(fmt.Sprintf(
+			"Step: %s\n"+
+				"  Count:   %d\n"+
+				"  Total:   %v\n"+
+				"  Average: %v\n"+
+				"  Min:     %v\n"+
+				"  Max:     %v\n",
+			agg.StepName,
+			agg.Count,
+			agg.Total.Round(time.Millisecond),
+			agg.Average.Round(time.Millisecond),
+			agg.Min.Round(time.Millisecond),
+			agg.Max.Round(time.Millisecond),
+		))
+	}
+
+	return sb.String()
+}
+
+// Merge folds the steps and aggregates recorded by other into pt, e.g. to
+// add the timings of a single ticker to those of the whole run. The steps are
+// copied, so spans of other that are still running do not affect pt.
+func (pt *PerformanceTracker) Merge(other *PerformanceTracker) {
+	if other == nil || other == pt {
+		return
+	}
+
+	other.mu.Lock()
+	steps := make([]*StepTiming, 0, len(other.steps))
+	for _, step := range other.steps {
+		steps = append(steps, copyStep(step))
+	}
+	aggregates := make([]StepAggregate, 0, len(other.aggregates))
+	for _, agg := range other.aggregates {
+		aggregates = append(aggregates, *agg)
+	}
+	other.mu.Unlock()
+
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	pt.steps = append(pt.steps, steps...)
+	for _, o := range aggregates {
+		agg, exists := pt.aggregates[o.StepName]
+		if !exists {
+			merged := o
+			pt.aggregates[o.StepName] = &merged
+			continue
+		}
+
+		agg.Count += o.Count
+		agg.Total += o.Total
+		agg.Average = agg.Total / time.Duration(agg.Count)
+		if o.Min < agg.Min {
+			agg.Min = o.Min
+		}
+		if o.Max > agg.Max {
+			agg.Max = o.Max
+		}
+	}
+}
+
+// copyStep returns a deep copy of step and its sub-steps.
+func copyStep(step *StepTiming) *StepTiming {
+	c := *step
+	c.SubSteps = make([]*StepTiming, 0, len(step.SubSteps))
+	for _, sub := range step.SubSteps {
+		c.SubSteps = append(c.SubSteps, copyStep(sub))
+	}
+	return &c
+}
@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPerformanceTrackerConcurrentSpans(t *testing.T) {
+	pt := NewPerformanceTracker()
+
+	const goroutines = 20
+	const subSteps = 5
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			span := pt.Start(fmt.Sprintf("ticker-%d", i))
+			for j := 0; j < subSteps; j++ {
+				sub := span.Start("page")
+				sub.Start("parse").End()
+				sub.End()
+			}
+			span.End()
+			pt.GenerateReport()
+			pt.Aggregates()
+		}(i)
+	}
+	wg.Wait()
+
+	if len(pt.steps) != goroutines {
+		t.Fatalf("got %d top-level steps, want %d", len(pt.steps), goroutines)
+	}
+	for _, step := range pt.steps {
+		if !step.Ended {
+			t.Errorf("step %s not ended", step.Name)
+		}
+		if len(step.SubSteps) != subSteps {
+			t.Errorf("step %s has %d sub-steps, want %d", step.Name, len(step.SubSteps), subSteps)
+		}
+		for _, sub := range step.SubSteps {
+			if sub.Name != "page" || len(sub.SubSteps) != 1 || sub.SubSteps[0].Name != "parse" {
+				t.Errorf("step %s has unexpected sub-step tree under %s", step.Name, sub.Name)
+			}
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, agg := range pt.Aggregates() {
+		counts[agg.StepName] = agg.Count
+	}
+	if counts["page"] != goroutines*subSteps || counts["parse"] != goroutines*subSteps {
+		t.Errorf("got page/parse counts %d/%d, want %d", counts["page"], counts["parse"], goroutines*subSteps)
+	}
+}
+
+func TestSpanEndTwice(t *testing.T) {
+	pt := NewPerformanceTracker()
+	span := pt.Start("step")
+	first := span.End()
+	time.Sleep(2 * time.Millisecond)
+	second := span.End()
+
+	if first != second {
+		t.Errorf("second End returned %v, want %v", second, first)
+	}
+	aggregates := pt.Aggregates()
+	if len(aggregates) != 1 || aggregates[0].Count != 1 {
+		t.Fatalf("got aggregates %+v, want one step counted once", aggregates)
+	}
+	if aggregates[0].Total != first {
+		t.Errorf("got total %v, want %v", aggregates[0].Total, first)
+	}
+}
+
+func TestMergeCopiesSteps(t *testing.T) {
+	source := NewPerformanceTracker()
+	running := source.Start("ticker")
+	running.Start("done").End()
+
+	dest := NewPerformanceTracker()
+	dest.Merge(source)
+
+	// Changes to the source after the merge must not reach the copy.
+	running.Start("late").End()
+	running.End()
+
+	if len(dest.steps) != 1 {
+		t.Fatalf("got %d steps, want 1", len(dest.steps))
+	}
+	step := dest.steps[0]
+	if step == source.steps[0] {
+		t.Fatal("merged step shares its pointer with the source")
+	}
+	if step.Ended || step.Duration != 0 {
+		t.Errorf("merged step ended (duration %v) when the source span ended", step.Duration)
+	}
+	if len(step.SubSteps) != 1 || step.SubSteps[0].Name != "done" {
+		t.Errorf("merged step has sub-steps %v, want only done", step.SubSteps)
+	}
+	for _, agg := range dest.Aggregates() {
+		if agg.StepName != "done" {
+			t.Errorf("aggregate %s recorded after the merge reached the destination", agg.StepName)
+		}
+	}
+}
+
+func TestAggregates(t *testing.T) {
+	pt := NewPerformanceTracker()
+	var durations []time.Duration
+	for i := 0; i < 3; i++ {
+		span := pt.Start("step")
+		time.Sleep(time.Duration(i+1) * time.Millisecond)
+		durations = append(durations, span.End())
+	}
+	pt.Start("other").End()
+
+	var step *StepAggregate
+	for _, agg := range pt.Aggregates() {
+		if agg.StepName == "step" {
+			agg := agg
+			step = &agg
+		}
+	}
+	if step == nil {
+		t.Fatal("no aggregate for step")
+	}
+
+	var total time.Duration
+	min, max := durations[0], durations[0]
+	for _, d := range durations {
+		total += d
+		if d < min {
+			min = d
+		}
+		if d > max {
+			max = d
+		}
+	}
+	if step.Count != len(durations) {
+		t.Errorf("got count %d, want %d", step.Count, len(durations))
+	}
+	if step.Total != total || step.Min != min || step.Max != max {
+		t.Errorf("got total/min/max %v/%v/%v, want %v/%v/%v", step.Total, step.Min, step.Max, total, min, max)
+	}
+	if step.Average != total/time.Duration(len(durations)) {
+		t.Errorf("got average %v, want %v", step.Average, total/time.Duration(len(durations)))
+	}
+
+	merged := NewPerformanceTracker()
+	merged.Merge(pt)
+	merged.Merge(pt)
+	for _, agg := range merged.Aggregates() {
+		if agg.StepName == "step" && (agg.Count != 2*step.Count || agg.Total != 2*step.Total || agg.Min != step.Min || agg.Max != step.Max) {
+			t.Errorf("merged aggregate %+v does not match twice %+v", agg, *step)
+		}
+	}
+}
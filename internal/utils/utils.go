@@ -1,28 +1,215 @@
-package utils
-
-import (
-	"encoding/csv"
-	"os"
-)
-
-// ReadTickersFromCSV reads ticker symbols from a CSV file.
-func ReadTickersFromCSV(filePath string) ([]string, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, err
-	}
-
-	var tickers []string
-	for _, record := range records[1:] { // Skip header
-		tickers = append(tickers, record[0]) // Assuming ticker is in the first column
-	}
-
-	return tickers, nil
-}
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"webscraper/models"
+)
+
+// Ticker is a row of the ticker file: the company and the settings that
+// apply to it alone.
+type Ticker struct {
+	models.Company
+	// FromDate overrides scraper.fromDate for this ticker (YYYY-MM-DD);
+	// empty uses the configured date.
+	FromDate string
+	// Enabled is false for tickers kept in the file but not scraped.
+	Enabled bool
+}
+
+// tickerColumns maps the accepted header names of each ticker file column,
+// compared case-insensitively without spaces, dashes and underscores.
+var tickerColumns = map[string][]string{
+	"symbol":   {"ticker", "symbol", "code"},
+	"sector":   {"sector"},
+	"name":     {"name", "company", "companyname"},
+	"fromDate": {"fromdate", "from"},
+	"enabled":  {"enabled", "active"},
+}
+
+// ReadTickers reads the ticker file at filePath. Columns are found by their
+// header, so they may come in any order; only a Ticker (or Symbol) column is
+// required. Rows without a symbol are skipped, and symbols are upper-cased.
+func ReadTickers(filePath string) ([]Ticker, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	columns, _, err := readTickerHeader(filePath, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var tickers []Ticker
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		cell := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		t := Ticker{
+			Company: models.Company{
+				Symbol: strings.ToUpper(cell("symbol")),
+				Sector: cell("sector"),
+				Name:   cell("name"),
+			},
+			FromDate: cell("fromDate"),
+			Enabled:  true,
+		}
+		if t.Symbol == "" {
+			continue
+		}
+		if !models.ValidSymbol(t.Symbol) {
+			return nil, fmt.Errorf("%s line %d: invalid ticker symbol %q", filePath, line, t.Symbol)
+		}
+		if t.FromDate != "" {
+			if _, err := time.Parse(DateLayout, t.FromDate); err != nil {
+				return nil, fmt.Errorf("%s line %d: invalid from date %q (want YYYY-MM-DD)", filePath, line, t.FromDate)
+			}
+		}
+		if enabled := cell("enabled"); enabled != "" {
+			switch strings.ToLower(enabled) {
+			case "true", "yes", "y", "1":
+			case "false", "no", "n", "0":
+				t.Enabled = false
+			default:
+				return nil, fmt.Errorf("%s line %d: invalid enabled value %q (want true or false)", filePath, line, enabled)
+			}
+		}
+		tickers = append(tickers, t)
+	}
+	return tickers, nil
+}
+
+// TickerColumns returns the index of each column found in the header of the
+// ticker file at filePath, keyed by "symbol", "sector", "name", "fromDate"
+// and "enabled", and the number of columns in the header.
+func TickerColumns(filePath string) (map[string]int, int, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	return readTickerHeader(filePath, reader)
+}
+
+// readTickerHeader reads the header row of a ticker file and maps its
+// columns, also returning how many it has. The Ticker column is required.
+func readTickerHeader(filePath string, reader *csv.Reader) (map[string]int, int, error) {
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, 0, fmt.Errorf("%s is empty", filePath)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		key := strings.ToLower(strings.NewReplacer(" ", "", "_", "", "-", "", "\ufeff", "").Replace(name))
+		for column, names := range tickerColumns {
+			for _, n := range names {
+				if key == n {
+					if _, dup := columns[column]; !dup {
+						columns[column] = i
+					}
+				}
+			}
+		}
+	}
+	if _, ok := columns["symbol"]; !ok {
+		return nil, 0, fmt.Errorf("%s has no Ticker column in its header %q", filePath, strings.Join(header, ","))
+	}
+	return columns, len(header), nil
+}
+
+// ReadTickersFromCSV reads the symbols of the enabled tickers in a ticker
+// file.
+func ReadTickersFromCSV(filePath string) ([]string, error) {
+	tickers, err := ReadTickers(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return Symbols(EnabledTickers(tickers)), nil
+}
+
+// EnabledTickers returns the tickers that are enabled.
+func EnabledTickers(tickers []Ticker) []Ticker {
+	var enabled []Ticker
+	for _, t := range tickers {
+		if t.Enabled {
+			enabled = append(enabled, t)
+		}
+	}
+	return enabled
+}
+
+// FilterSectors returns the tickers in any of the comma-separated sectors,
+// compared case-insensitively. An empty list returns all tickers.
+func FilterSectors(tickers []Ticker, sectors string) []Ticker {
+	wanted := make(map[string]bool)
+	for _, sector := range strings.Split(sectors, ",") {
+		if sector = strings.TrimSpace(sector); sector != "" {
+			wanted[strings.ToLower(sector)] = true
+		}
+	}
+	if len(wanted) == 0 {
+		return tickers
+	}
+
+	var filtered []Ticker
+	for _, t := range tickers {
+		if wanted[strings.ToLower(t.Sector)] {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
+
+// UniqueTickers returns tickers without repeated symbols, keeping the first
+// entry of each and its settings.
+func UniqueTickers(tickers []Ticker) []Ticker {
+	seen := make(map[string]bool, len(tickers))
+	unique := make([]Ticker, 0, len(tickers))
+	for _, t := range tickers {
+		if seen[t.Symbol] {
+			continue
+		}
+		seen[t.Symbol] = true
+		unique = append(unique, t)
+	}
+	return unique
+}
+
+// Symbols returns the symbols of tickers.
+func Symbols(tickers []Ticker) []string {
+	symbols := make([]string, len(tickers))
+	for i, t := range tickers {
+		symbols[i] = t.Symbol
+	}
+	return symbols
+}
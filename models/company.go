@@ -0,0 +1,192 @@
+package models
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Company is a company listed on the ISX, as discovered from the portal.
+type Company struct {
+	Symbol string
+	Name   string
+	Sector string // empty when the portal page did not say
+}
+
+// RawLink is the target and text of a link on a portal page.
+type RawLink struct {
+	Href string
+	Text string
+}
+
+// RawTable holds the cell text of a table on a portal page, one slice per
+// row, header row included.
+type RawTable struct {
+	Rows [][]string
+}
+
+// symbolPattern matches ISX ticker symbols, e.g. "BBOB" or "IBSD".
+var symbolPattern = regexp.MustCompile(`^[A-Z0-9]{2,12}$`)
+
+// ValidSymbol reports whether symbol is a valid upper-case ticker symbol.
+// Symbols are sent to the portal and used in file names, so anything read
+// from a ticker file, the command line or a request is checked with it.
+func ValidSymbol(symbol string) bool {
+	return symbolPattern.MatchString(symbol)
+}
+
+// stripQuote matches the price and change that follow a company's short name
+// in the portal's ticker strip, e.g. "Baghdad 4.23  0.71%".
+var stripQuote = regexp.MustCompile(`\s+-?[\d.,]+\s+-?[\d.,]+%?\s*$`)
+
+// ParseCompanies builds the list of listed companies from the links and
+// tables of the portal's pages, sorted by symbol.
+//
+// Tables are read by their header row: a column titled code, symbol or
+// ticker holds the symbol, a name or company column the name and a sector
+// column the sector. A row with a single filled cell is taken as a sector
+// heading for the rows below it, as on the listed-companies page. Links to a
+// company profile (companyCode=...), such as those in the ticker strip, add
+// any company the tables missed, named after the link text.
+func ParseCompanies(links []RawLink, tables []RawTable) []Company {
+	bySymbol := make(map[string]*Company)
+	add := func(c Company) {
+		c.Symbol = strings.ToUpper(strings.TrimSpace(c.Symbol))
+		if !symbolPattern.MatchString(c.Symbol) {
+			return
+		}
+		existing, ok := bySymbol[c.Symbol]
+		if !ok {
+			bySymbol[c.Symbol] = &c
+			return
+		}
+		if existing.Name == "" {
+			existing.Name = c.Name
+		}
+		if existing.Sector == "" {
+			existing.Sector = c.Sector
+		}
+	}
+
+	for _, table := range tables {
+		for _, c := range companiesFromTable(table) {
+			add(c)
+		}
+	}
+	for _, link := range links {
+		if symbol := companyCode(link.Href); symbol != "" {
+			add(Company{Symbol: symbol, Name: stripName(link.Text)})
+		}
+	}
+
+	companies := make([]Company, 0, len(bySymbol))
+	for _, c := range bySymbol {
+		companies = append(companies, *c)
+	}
+	sort.Slice(companies, func(i, j int) bool {
+		return companies[i].Symbol < companies[j].Symbol
+	})
+	return companies
+}
+
+// companiesFromTable reads the companies of a table whose header row has a
+// symbol column. Other tables yield nothing.
+func companiesFromTable(table RawTable) []Company {
+	if len(table.Rows) < 2 {
+		return nil
+	}
+	symbolCol, nameCol, sectorCol := -1, -1, -1
+	for i, cell := range table.Rows[0] {
+		header := strings.ToLower(strings.TrimSpace(cell))
+		switch {
+		case symbolCol < 0 && (strings.Contains(header, "code") || strings.Contains(header, "symbol") || strings.Contains(header, "ticker")):
+			symbolCol = i
+		case sectorCol < 0 && strings.Contains(header, "sector"):
+			sectorCol = i
+		case nameCol < 0 && (strings.Contains(header, "name") || strings.Contains(header, "company")):
+			nameCol = i
+		}
+	}
+	if symbolCol < 0 {
+		return nil
+	}
+
+	var companies []Company
+	sector := ""
+	for _, row := range table.Rows[1:] {
+		if heading, ok := singleCell(row); ok {
+			sector = heading
+			continue
+		}
+		if symbolCol >= len(row) {
+			continue
+		}
+		c := Company{Symbol: row[symbolCol], Sector: sector}
+		if nameCol >= 0 && nameCol < len(row) {
+			c.Name = strings.TrimSpace(row[nameCol])
+		}
+		if sectorCol >= 0 && sectorCol < len(row) {
+			c.Sector = strings.TrimSpace(row[sectorCol])
+		}
+		companies = append(companies, c)
+	}
+	return companies
+}
+
+// singleCell returns the text of a row with exactly one non-empty cell.
+func singleCell(row []string) (string, bool) {
+	text := ""
+	for _, cell := range row {
+		cell = strings.TrimSpace(cell)
+		if cell == "" {
+			continue
+		}
+		if text != "" {
+			return "", false
+		}
+		text = cell
+	}
+	return text, text != ""
+}
+
+// companyCode returns the companyCode parameter of a profile link, or "".
+func companyCode(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(u.Query().Get("companyCode"))
+}
+
+// stripName removes the price and change from a ticker strip entry.
+func stripName(text string) string {
+	return strings.TrimSpace(stripQuote.ReplaceAllString(strings.TrimSpace(text), ""))
+}
+
+// DiffCompanies compares discovered companies with the known ticker
+// symbols, returning the companies that are not known yet and the known
+// symbols that were not discovered.
+func DiffCompanies(known []string, companies []Company) (added []Company, missing []string) {
+	listed := make(map[string]bool, len(companies))
+	for _, c := range companies {
+		listed[c.Symbol] = true
+	}
+	isKnown := make(map[string]bool, len(known))
+	for _, symbol := range known {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if isKnown[symbol] {
+			continue
+		}
+		isKnown[symbol] = true
+		if !listed[symbol] {
+			missing = append(missing, symbol)
+		}
+	}
+	for _, c := range companies {
+		if !isKnown[c.Symbol] {
+			added = append(added, c)
+		}
+	}
+	return added, missing
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// readFixturePage extracts the links and tables of a saved portal page the
+// way the scraper's page script does: link targets with their text, and the
+// text of each table cell with whitespace collapsed.
+func readFixturePage(t *testing.T, name string) ([]RawLink, []RawTable) {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("..", "test", "testdata", "replay", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	html := string(content)
+
+	tags := regexp.MustCompile(`<[^>]*>`)
+	space := regexp.MustCompile(`\s+`)
+	text := func(s string) string {
+		return strings.TrimSpace(space.ReplaceAllString(tags.ReplaceAllString(s, ""), " "))
+	}
+
+	var links []RawLink
+	for _, m := range regexp.MustCompile(`<a href="([^"]*companyCode=[^"]*)">(.*?)</a>`).FindAllStringSubmatch(html, -1) {
+		links = append(links, RawLink{Href: "http://replay/" + strings.ReplaceAll(m[1], "&amp;", "&"), Text: text(m[2])})
+	}
+
+	var tables []RawTable
+	for _, table := range regexp.MustCompile(`(?s)<table.*?</table>`).FindAllString(html, -1) {
+		var raw RawTable
+		for _, row := range regexp.MustCompile(`(?s)<tr>(.*?)</tr>`).FindAllStringSubmatch(table, -1) {
+			var cells []string
+			for _, cell := range regexp.MustCompile(`(?s)<t[dh][^>]*>(.*?)</t[dh]>`).FindAllStringSubmatch(row[1], -1) {
+				cells = append(cells, text(cell[1]))
+			}
+			raw.Rows = append(raw.Rows, cells)
+		}
+		tables = append(tables, raw)
+	}
+	return links, tables
+}
+
+func TestParseCompaniesFixture(t *testing.T) {
+	links, tables := readFixturePage(t, "listedCompanies.html")
+	companies := ParseCompanies(links, tables)
+
+	want := []Company{
+		{Symbol: "BBOB", Name: "Bank Of Baghdad", Sector: "Banks"},
+		{Symbol: "BGUC", Name: "Gulf Commercial Bank", Sector: "Banks"},
+		{Symbol: "BIBI", Name: "Investment Bank of Iraq", Sector: "Banks"},
+		{Symbol: "BIIB", Name: "Iraqi Islamic Bank", Sector: "Banks"},
+		{Symbol: "BMNS", Name: "Al-Mansour Bank", Sector: "Banks"},
+		{Symbol: "BNOI", Name: "National Bank Of Iraq", Sector: "Banks"},
+		{Symbol: "BROI", Name: "Credit Bank Of Iraq", Sector: "Banks"},
+		{Symbol: "BSUC", Name: "Sumer Commerical Bank", Sector: "Banks"},
+		{Symbol: "BTRI", Name: "Trans Iraq Bank", Sector: "Banks"},
+		{Symbol: "IBSD", Name: "Baghdad Soft Drinks", Sector: "Industry"},
+		// Only in the ticker strip: named after the link, price stripped
+		{Symbol: "SKTA", Name: "Kindfit"},
+		{Symbol: "TASC", Name: "Asia Cell", Sector: "Telecommunication"},
+	}
+	if !reflect.DeepEqual(companies, want) {
+		t.Errorf("ParseCompanies returned\n%+v\nwant\n%+v", companies, want)
+	}
+}
+
+func TestParseCompaniesTableColumns(t *testing.T) {
+	tables := []RawTable{
+		{Rows: [][]string{{"Date", "Close"}, {"23/12/2024", "4.23"}}},
+		{Rows: [][]string{
+			{"Company Name", "Sector", "Symbol"},
+			{"Bank Of Baghdad", "Banks", " bbob "},
+			{"Not a ticker", "Banks", "not a ticker"},
+			{"Short row"},
+		}},
+	}
+	links := []RawLink{
+		{Href: "companyprofilecontainer.html?companyCode=BBOB%20", Text: "Baghdad 4.23  0.71%"},
+		{Href: "companyprofilecontainer.html?companyCode=IBSD", Text: "Pepsi -4.17  -0.48%"},
+		{Href: "other.html?id=1", Text: "Elsewhere"},
+	}
+
+	want := []Company{
+		{Symbol: "BBOB", Name: "Bank Of Baghdad", Sector: "Banks"},
+		{Symbol: "IBSD", Name: "Pepsi"},
+	}
+	if got := ParseCompanies(links, tables); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCompanies returned %+v, want %+v", got, want)
+	}
+}
+
+func TestDiffCompanies(t *testing.T) {
+	links, tables := readFixturePage(t, "listedCompanies.html")
+	companies := ParseCompanies(links, tables)
+
+	known := []string{"BBOB", "bmns", " TASC ", "BBOB", "XXXX", "IBSD", "ZZZZ"}
+	added, missing := DiffCompanies(known, companies)
+
+	var addedSymbols []string
+	for _, c := range added {
+		addedSymbols = append(addedSymbols, c.Symbol)
+	}
+	wantAdded := []string{"BGUC", "BIBI", "BIIB", "BNOI", "BROI", "BSUC", "BTRI", "SKTA"}
+	if !reflect.DeepEqual(addedSymbols, wantAdded) {
+		t.Errorf("added = %v, want %v", addedSymbols, wantAdded)
+	}
+	if wantMissing := []string{"XXXX", "ZZZZ"}; !reflect.DeepEqual(missing, wantMissing) {
+		t.Errorf("missing = %v, want %v", missing, wantMissing)
+	}
+	if added[0].Name != "Gulf Commercial Bank" || added[0].Sector != "Banks" {
+		t.Errorf("added company %+v lost its name or sector", added[0])
+	}
+
+	added, missing = DiffCompanies(nil, nil)
+	if added != nil || missing != nil {
+		t.Errorf("DiffCompanies(nil, nil) = %v, %v, want nothing", added, missing)
+	}
+}
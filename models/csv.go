@@ -0,0 +1,187 @@
+package models
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CSV schema versions understood by ReadCSV.
+const (
+	// SchemaV1 is the original layout: DD/MM/YYYY dates, a formatted
+	// "Change%" column and counts that may carry thousands separators.
+	SchemaV1 = 1
+	// SchemaV2 uses ISO dates, snake_case headers and plain numbers.
+	SchemaV2 = 2
+)
+
+// SchemaVersion is the CSV layout written by WriteCSV. Downstream tools read
+// the original layout, so SchemaV2 is only written when asked for.
+const SchemaVersion = SchemaV1
+
+// LegacyHeader is the header row of SchemaV1 files.
+var LegacyHeader = []string{"Date", "Open", "High", "Low", "Close", "Change", "Change%", "Volume", "T.Shares", "Trades"}
+
+// Header is the header row of SchemaV2 files.
+var Header = []string{"date", "open", "high", "low", "close", "change", "change_pct", "volume", "total_shares", "trades"}
+
+// DetectSchema returns the schema version identified by a CSV header row.
+func DetectSchema(header []string) (int, error) {
+	switch {
+	case equalFold(header, Header):
+		return SchemaV2, nil
+	case equalFold(header, LegacyHeader):
+		return SchemaV1, nil
+	}
+	return 0, fmt.Errorf("unrecognized CSV header %q", strings.Join(header, ","))
+}
+
+// ReadCSV reads price history written in any known schema version and
+// returns the records along with the detected version.
+func ReadCSV(r io.Reader) ([]StockData, int, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, SchemaVersion, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	version, err := DetectSchema(header)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, version, err
+	}
+
+	rows := make([]RawRecord, 0, len(records))
+	for i, record := range records {
+		if len(record) != len(Header) {
+			return nil, version, fmt.Errorf("line %d: expected %d columns, got %d", i+2, len(Header), len(record))
+		}
+		rows = append(rows, rawFromCSV(record))
+	}
+
+	data, err := ParseRecords(rows)
+	if err != nil {
+		return nil, version, err
+	}
+	return data, version, nil
+}
+
+// WriteCSV writes price history in the default schema version.
+func WriteCSV(w io.Writer, data []StockData) error {
+	return WriteCSVVersion(w, data, SchemaVersion)
+}
+
+// WriteCSVVersion writes price history in the given schema version.
+func WriteCSVVersion(w io.Writer, data []StockData, version int) error {
+	header := Header
+	switch version {
+	case SchemaV1:
+		header = LegacyHeader
+	case SchemaV2:
+	default:
+		return fmt.Errorf("unknown CSV schema version %d", version)
+	}
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write headers: %v", err)
+	}
+	for _, record := range data {
+		if err := writer.Write(MarshalRecord(record, version)); err != nil {
+			return fmt.Errorf("failed to write record: %v", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// MarshalRecord formats a record as a CSV row of the given schema version.
+func MarshalRecord(record StockData, version int) []string {
+	if version == SchemaV1 {
+		return []string{
+			record.Date.Format(PortalDateLayout),
+			record.OpenPrice.String(),
+			record.HighPrice.String(),
+			record.LowPrice.String(),
+			record.ClosePrice.String(),
+			fmt.Sprintf("%.3f", record.Change.Float64()),
+			fmt.Sprintf("%.2f%%", record.ChangePerc),
+			strconv.FormatInt(record.Volume, 10),
+			strconv.FormatInt(record.TotalShares, 10),
+			strconv.FormatInt(record.NumTrades, 10),
+		}
+	}
+	return []string{
+		record.Date.Format(DateLayout),
+		record.OpenPrice.String(),
+		record.HighPrice.String(),
+		record.LowPrice.String(),
+		record.ClosePrice.String(),
+		record.Change.String(),
+		strconv.FormatFloat(record.ChangePerc, 'f', 2, 64),
+		strconv.FormatInt(record.Volume, 10),
+		strconv.FormatInt(record.TotalShares, 10),
+		strconv.FormatInt(record.NumTrades, 10),
+	}
+}
+
+// FromLegacyRecord parses one data row of a SchemaV1 file.
+func FromLegacyRecord(record []string) (StockData, error) {
+	if len(record) != len(LegacyHeader) {
+		return StockData{}, fmt.Errorf("expected %d columns, got %d", len(LegacyHeader), len(record))
+	}
+	data, errs := ParseRecord(0, rawFromCSV(record))
+	if len(errs) > 0 {
+		return StockData{}, errs
+	}
+	return data, nil
+}
+
+// ConvertLegacyCSV rewrites a SchemaV1 file read from r as SchemaV2 to w.
+// Files already in SchemaV2 are rewritten as-is.
+func ConvertLegacyCSV(r io.Reader, w io.Writer) error {
+	data, _, err := ReadCSV(r)
+	if err != nil {
+		return err
+	}
+	return WriteCSVVersion(w, data, SchemaV2)
+}
+
+// rawFromCSV maps a CSV row to its cells. Both schema versions share the same
+// column order.
+func rawFromCSV(record []string) RawRecord {
+	return RawRecord{
+		Date:        record[0],
+		OpenPrice:   record[1],
+		HighPrice:   record[2],
+		LowPrice:    record[3],
+		ClosePrice:  record[4],
+		Change:      record[5],
+		ChangePerc:  record[6],
+		Volume:      record[7],
+		TotalShares: record[8],
+		NumTrades:   record[9],
+	}
+}
+
+func equalFold(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(strings.TrimSpace(a[i]), b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// Revision records a trading day whose saved values differ from the values
+// the portal now reports, e.g. after a correction by the exchange.
+type Revision struct {
+	Date time.Time
+	Old  StockData
+	New  StockData
+}
+
+// MergeResult is the outcome of merging scraped rows into saved history.
+type MergeResult struct {
+	// Data holds one record per trading date, newest first.
+	Data []StockData
+	// Added is the number of dates that were not in the saved history.
+	Added int
+	// Revised lists saved dates whose values were replaced.
+	Revised []Revision
+	// Duplicates counts rows dropped because their date was already seen
+	// in the same input.
+	Duplicates int
+}
+
+// Merge combines saved history with freshly scraped rows, keyed on trading
+// date. Scraped rows win over saved rows for the same date, and the first
+// occurrence of a date within either input wins over later ones. The result
+// is in strict reverse-chronological order regardless of the input order.
+func Merge(existing, scraped []StockData) MergeResult {
+	var result MergeResult
+
+	byDate := make(map[time.Time]StockData, len(existing)+len(scraped))
+	saved := make(map[time.Time]bool, len(existing))
+	for _, record := range existing {
+		key := dateKey(record.Date)
+		if saved[key] {
+			result.Duplicates++
+			continue
+		}
+		saved[key] = true
+		byDate[key] = record
+	}
+
+	seen := make(map[time.Time]bool, len(scraped))
+	for _, record := range scraped {
+		key := dateKey(record.Date)
+		if seen[key] {
+			result.Duplicates++
+			continue
+		}
+		seen[key] = true
+
+		if old, ok := byDate[key]; ok && saved[key] {
+			if !SameTrading(old, record) {
+				result.Revised = append(result.Revised, Revision{Date: key, Old: old, New: record})
+			}
+		} else {
+			result.Added++
+		}
+		byDate[key] = record
+	}
+
+	result.Data = make([]StockData, 0, len(byDate))
+	for _, record := range byDate {
+		result.Data = append(result.Data, record)
+	}
+	sort.Slice(result.Data, func(i, j int) bool {
+		return result.Data[i].Date.After(result.Data[j].Date)
+	})
+	sort.Slice(result.Revised, func(i, j int) bool {
+		return result.Revised[i].Date.After(result.Revised[j].Date)
+	})
+	return result
+}
+
+// SameTrading reports whether a and b hold the same traded values. Change and
+// ChangePerc are ignored since they are derived from the neighbouring day.
+func SameTrading(a, b StockData) bool {
+	return a.OpenPrice == b.OpenPrice &&
+		a.HighPrice == b.HighPrice &&
+		a.LowPrice == b.LowPrice &&
+		a.ClosePrice == b.ClosePrice &&
+		a.Volume == b.Volume &&
+		a.TotalShares == b.TotalShares &&
+		a.NumTrades == b.NumTrades
+}
+
+// FilterDates returns the records dated from through to, in their original
+// order. A zero from or to leaves that end of the range open.
+func FilterDates(data []StockData, from, to time.Time) []StockData {
+	filtered := make([]StockData, 0, len(data))
+	for _, record := range data {
+		key := dateKey(record.Date)
+		if !from.IsZero() && key.Before(dateKey(from)) {
+			continue
+		}
+		if !to.IsZero() && key.After(dateKey(to)) {
+			continue
+		}
+		filtered = append(filtered, record)
+	}
+	return filtered
+}
+
+// dateKey normalizes t to midnight UTC of its calendar day so records parsed
+// from different layouts compare equal.
+func dateKey(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// day returns a record for the given day of December 2024 closing at close.
+func day(d int, close Price) StockData {
+	return StockData{
+		Date:        time.Date(2024, 12, d, 0, 0, 0, 0, time.UTC),
+		OpenPrice:   close,
+		HighPrice:   close,
+		LowPrice:    close,
+		ClosePrice:  close,
+		Volume:      1000,
+		TotalShares: 500,
+		NumTrades:   10,
+	}
+}
+
+// withChange returns record with its derived change fields set.
+func withChange(record StockData, change Price, perc float64) StockData {
+	record.Change = change
+	record.ChangePerc = perc
+	return record
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name       string
+		existing   []StockData
+		scraped    []StockData
+		want       []StockData
+		added      int
+		revised    []int // days of the revised dates, newest first
+		duplicates int
+	}{
+		{
+			name:    "nothing saved",
+			scraped: []StockData{day(23, 4230), day(22, 4200)},
+			want:    []StockData{day(23, 4230), day(22, 4200)},
+			added:   2,
+		},
+		{
+			name:     "new days added to saved history",
+			existing: []StockData{day(20, 4100), day(19, 4000)},
+			scraped:  []StockData{day(23, 4230), day(22, 4200)},
+			want:     []StockData{day(23, 4230), day(22, 4200), day(20, 4100), day(19, 4000)},
+			added:    2,
+		},
+		{
+			name:     "overlap with unchanged values",
+			existing: []StockData{day(22, 4200), day(20, 4100)},
+			scraped:  []StockData{day(23, 4230), day(22, 4200)},
+			want:     []StockData{day(23, 4230), day(22, 4200), day(20, 4100)},
+			added:    1,
+		},
+		{
+			name:     "revised rows replace saved ones",
+			existing: []StockData{day(22, 4200), day(20, 4100), day(19, 4000)},
+			scraped:  []StockData{day(22, 4250), day(20, 4100), day(19, 3990)},
+			want:     []StockData{day(22, 4250), day(20, 4100), day(19, 3990)},
+			revised:  []int{22, 19},
+		},
+		{
+			name:     "a changed derived value is not a revision",
+			existing: []StockData{withChange(day(22, 4200), 0, 0)},
+			scraped:  []StockData{withChange(day(22, 4200), 100, 2.44)},
+			want:     []StockData{withChange(day(22, 4200), 100, 2.44)},
+		},
+		{
+			name:       "duplicate dates in one scrape keep the first",
+			scraped:    []StockData{day(23, 4230), day(22, 4200), day(23, 9999), day(22, 1)},
+			want:       []StockData{day(23, 4230), day(22, 4200)},
+			added:      2,
+			duplicates: 2,
+		},
+		{
+			name:       "duplicate saved dates keep the first",
+			existing:   []StockData{day(20, 4100), day(20, 1)},
+			want:       []StockData{day(20, 4100)},
+			duplicates: 1,
+		},
+		{
+			name:     "result is newest first whatever the input order",
+			existing: []StockData{day(2, 3800), day(18, 3950), day(10, 3900)},
+			scraped:  []StockData{day(19, 4000), day(23, 4230), day(1, 3790)},
+			want:     []StockData{day(23, 4230), day(19, 4000), day(18, 3950), day(10, 3900), day(2, 3800), day(1, 3790)},
+			added:    3,
+		},
+		{
+			name:     "dates match across time zones and times of day",
+			existing: []StockData{day(22, 4200)},
+			scraped: []StockData{func() StockData {
+				record := day(22, 4250)
+				record.Date = time.Date(2024, 12, 22, 13, 30, 0, 0, time.FixedZone("AST", 3*60*60))
+				return record
+			}()},
+			want: []StockData{func() StockData {
+				record := day(22, 4250)
+				record.Date = time.Date(2024, 12, 22, 13, 30, 0, 0, time.FixedZone("AST", 3*60*60))
+				return record
+			}()},
+			revised: []int{22},
+		},
+		{
+			name: "nothing at all",
+			want: []StockData{},
+		},
+	}
+	for _, tt := range tests {
+		result := Merge(tt.existing, tt.scraped)
+		if !reflect.DeepEqual(result.Data, tt.want) {
+			t.Errorf("%s: got data\n%+v\nwant\n%+v", tt.name, result.Data, tt.want)
+		}
+		if result.Added != tt.added {
+			t.Errorf("%s: got %d added, want %d", tt.name, result.Added, tt.added)
+		}
+		if result.Duplicates != tt.duplicates {
+			t.Errorf("%s: got %d duplicates, want %d", tt.name, result.Duplicates, tt.duplicates)
+		}
+		var revised []int
+		for _, r := range result.Revised {
+			revised = append(revised, r.Date.Day())
+		}
+		if !reflect.DeepEqual(revised, tt.revised) {
+			t.Errorf("%s: got revised days %v, want %v", tt.name, revised, tt.revised)
+		}
+	}
+}
+
+func TestMergeRevisionValues(t *testing.T) {
+	old, revised := day(22, 4200), day(22, 4250)
+	result := Merge([]StockData{old}, []StockData{revised})
+	if len(result.Revised) != 1 {
+		t.Fatalf("got %d revisions, want 1", len(result.Revised))
+	}
+	r := result.Revised[0]
+	if !r.Date.Equal(old.Date) || !reflect.DeepEqual(r.Old, old) || !reflect.DeepEqual(r.New, revised) {
+		t.Errorf("got revision %+v, want %s from %+v to %+v", r, old.Date, old, revised)
+	}
+}
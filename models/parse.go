@@ -0,0 +1,288 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CellError describes a table cell that could not be parsed.
+type CellError struct {
+	Row    int
+	Column string
+	Value  string
+	Err    error
+}
+
+func (e *CellError) Error() string {
+	return fmt.Sprintf("row %d, column %s: cannot parse %q: %v", e.Row+1, e.Column, e.Value, e.Err)
+}
+
+func (e *CellError) Unwrap() error {
+	return e.Err
+}
+
+// errEmptyCell is returned for a required number cell that holds nothing,
+// such as a cell missing from a truncated or misaligned row.
+var errEmptyCell = errors.New("empty cell")
+
+// ParseErrors collects every cell error found while parsing a table.
+type ParseErrors []*CellError
+
+func (e ParseErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	msgs := make([]string, len(e))
+	for i, cellErr := range e {
+		msgs[i] = cellErr.Error()
+	}
+	return fmt.Sprintf("%d cells failed to parse: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// RawRecord holds the cell text of one history row, as extracted from the
+// portal's table or read from a CSV file.
+type RawRecord struct {
+	Date        string
+	OpenPrice   string
+	HighPrice   string
+	LowPrice    string
+	ClosePrice  string
+	Change      string
+	ChangePerc  string
+	Volume      string
+	TotalShares string
+	NumTrades   string
+}
+
+// ParseRecords converts raw rows into typed records. All cell errors are
+// collected and returned together as ParseErrors.
+func ParseRecords(rows []RawRecord) ([]StockData, error) {
+	data := make([]StockData, 0, len(rows))
+	var errs ParseErrors
+
+	for i, raw := range rows {
+		record, rowErrs := ParseRecord(i, raw)
+		if len(rowErrs) > 0 {
+			errs = append(errs, rowErrs...)
+			continue
+		}
+		data = append(data, record)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return data, nil
+}
+
+// ParseRecord converts a single raw row; row is only used to label errors.
+// Change and ChangePerc are optional since they are derived values.
+func ParseRecord(row int, raw RawRecord) (StockData, ParseErrors) {
+	var record StockData
+	var errs ParseErrors
+
+	cell := func(column, value string, parse func(string) error) {
+		if err := parse(value); err != nil {
+			errs = append(errs, &CellError{Row: row, Column: column, Value: value, Err: err})
+		}
+	}
+	price := func(dst *Price) func(string) error {
+		return func(v string) (err error) {
+			*dst, err = ParsePrice(v)
+			return err
+		}
+	}
+	count := func(dst *int64) func(string) error {
+		return func(v string) (err error) {
+			*dst, err = ParseCount(v)
+			return err
+		}
+	}
+
+	cell("Date", raw.Date, func(v string) (err error) {
+		record.Date, err = ParseDate(v)
+		return err
+	})
+	cell("Open", raw.OpenPrice, price(&record.OpenPrice))
+	cell("High", raw.HighPrice, price(&record.HighPrice))
+	cell("Low", raw.LowPrice, price(&record.LowPrice))
+	cell("Close", raw.ClosePrice, price(&record.ClosePrice))
+	if raw.Change != "" {
+		cell("Change", raw.Change, price(&record.Change))
+	}
+	if raw.ChangePerc != "" {
+		cell("Change%", raw.ChangePerc, func(v string) (err error) {
+			record.ChangePerc, err = ParsePercent(v)
+			return err
+		})
+	}
+	cell("Volume", raw.Volume, count(&record.Volume))
+	cell("T.Shares", raw.TotalShares, count(&record.TotalShares))
+	cell("Trades", raw.NumTrades, count(&record.NumTrades))
+
+	return record, errs
+}
+
+// ParseDate parses a trading date in the portal's DD/MM/YYYY format. ISO
+// YYYY-MM-DD dates are accepted as well.
+func ParseDate(s string) (time.Time, error) {
+	v := normalizeDigits(strings.TrimSpace(s))
+	if v == "" {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+	if t, err := time.Parse(PortalDateLayout, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(DateLayout, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected DD/MM/YYYY")
+	}
+	return t, nil
+}
+
+// ParsePrice parses a price such as "4.23", "1,250.5" or "٤٫٢٣", rounding it
+// half away from zero to the nearest thousandth. A dash, used by the portal on
+// no-trade days, parses as zero; an empty cell is an error.
+func ParsePrice(s string) (Price, error) {
+	v, noValue, err := normalizeNumber(s)
+	if err != nil {
+		return 0, err
+	}
+	if noValue {
+		return 0, nil
+	}
+
+	neg := false
+	switch {
+	case strings.HasPrefix(v, "-"):
+		neg = true
+		v = v[1:]
+	case strings.HasPrefix(v, "+"):
+		v = v[1:]
+	}
+
+	whole, frac, _ := strings.Cut(v, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("no digits")
+	}
+	if strings.Trim(whole+frac, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid number")
+	}
+	roundUp := false
+	if len(frac) > priceDecimals {
+		roundUp = frac[priceDecimals] >= '5'
+		frac = frac[:priceDecimals]
+	}
+	frac += strings.Repeat("0", priceDecimals-len(frac))
+
+	if whole == "" {
+		whole = "0"
+	}
+	w, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, fmt.Errorf("out of range")
+		}
+		return 0, fmt.Errorf("invalid number")
+	}
+	f, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number")
+	}
+	if roundUp {
+		f++
+	}
+	if w > (math.MaxInt64-f)/priceScale {
+		return 0, fmt.Errorf("out of range")
+	}
+
+	p := Price(w*priceScale + f)
+	if neg {
+		p = -p
+	}
+	return p, nil
+}
+
+// ParseCount parses a whole number such as "236,151,248". A dash parses as
+// zero; an empty cell is an error.
+func ParseCount(s string) (int64, error) {
+	v, noValue, err := normalizeNumber(s)
+	if err != nil {
+		return 0, err
+	}
+	if noValue {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, fmt.Errorf("out of range")
+		}
+		return 0, fmt.Errorf("invalid whole number")
+	}
+	return n, nil
+}
+
+// ParsePercent parses a percentage such as "0.71%" or "-1.08". A dash parses
+// as zero; an empty cell is an error.
+func ParsePercent(s string) (float64, error) {
+	v, noValue, err := normalizeNumber(s)
+	if err != nil {
+		return 0, err
+	}
+	if noValue {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage")
+	}
+	return f, nil
+}
+
+// normalizeNumber converts a table cell into plain ASCII number syntax: digits
+// are mapped to ASCII, thousands separators, spaces and a "%" suffix are
+// dropped and the Arabic decimal separator becomes ".". It reports whether the
+// cell is one of the dashes the portal shows for no value, and returns
+// errEmptyCell for an empty cell.
+func normalizeNumber(s string) (string, bool, error) {
+	v := normalizeDigits(strings.TrimSpace(s))
+	switch v {
+	case "":
+		return "", false, errEmptyCell
+	case "-", "--", "–", "—":
+		return "", true, nil
+	}
+
+	var sb strings.Builder
+	for _, r := range v {
+		switch r {
+		case ',', '٬', '،', ' ', '\u00a0', '%', '٪':
+			// thousands separators, spaces and percent signs
+		case '٫':
+			sb.WriteRune('.')
+		case '\u2212': // minus sign
+			sb.WriteRune('-')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String(), false, nil
+}
+
+// normalizeDigits maps Arabic-Indic and Eastern Arabic-Indic digits to ASCII.
+func normalizeDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '٠' && r <= '٩':
+			return '0' + (r - '٠')
+		case r >= '۰' && r <= '۹':
+			return '0' + (r - '۰')
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Price
+	}{
+		{"4.23", 4230},
+		{" 102 ", 102000},
+		{".5", 500},
+		{"+1.5", 1500},
+		{"-0.01", -10},
+		{"−0.01", -10},
+		{"1,250.5", 1250500},
+		{"1 250.5", 1250500},
+		{"1 250.5", 1250500},
+		{"٤٫٢٣", 4230},
+		{"١٬٢٥٠٫٥", 1250500},
+		{"۴.۲۳", 4230},
+		{"0.71%", 710},
+		{"-", 0},
+		{"--", 0},
+		{"—", 0},
+		// Rounded half away from zero to thousandths
+		{"4.2300", 4230},
+		{"4.2304", 4230},
+		{"4.2305", 4231},
+		{"-4.2305", -4231},
+		{"4.9996", 5000},
+		{"9223372036854775.807", 9223372036854775807},
+	}
+	for _, tt := range tests {
+		got, err := ParsePrice(tt.in)
+		if err != nil {
+			t.Errorf("ParsePrice(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePrice(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParsePriceInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"   ",
+		".",
+		"abc",
+		"4.2.3",
+		"--5",
+		"-+5",
+		"4.23x",
+		"9223372036854775.808",
+		"9223372036854775.8075",
+		"99999999999999999999",
+	}
+	for _, in := range tests {
+		if got, err := ParsePrice(in); err == nil {
+			t.Errorf("ParsePrice(%q) = %d, want an error", in, got)
+		}
+	}
+	if _, err := ParsePrice(""); !errors.Is(err, errEmptyCell) {
+		t.Errorf("ParsePrice(\"\") error = %v, want %v", err, errEmptyCell)
+	}
+}
+
+func TestParseCount(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"236,151,248", 236151248, false},
+		{"٢٣٦٬١٥١٬٢٤٨", 236151248, false},
+		{"1 000", 1000, false},
+		{"0", 0, false},
+		{"-", 0, false},
+		{"–", 0, false},
+		{"9223372036854775807", 9223372036854775807, false},
+		{"9223372036854775808", 0, true},
+		{"", 0, true},
+		{"1.5", 0, true},
+		{"many", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCount(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCount(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseCount(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParsePercent(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"0.71%", 0.71, false},
+		{"-1.08", -1.08, false},
+		{"−1.08 %", -1.08, false},
+		{"٠٫٧١٪", 0.71, false},
+		{"-", 0, false},
+		{"", 0, true},
+		{"%", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePercent(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePercent(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePercent(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	want := time.Date(2024, 12, 23, 0, 0, 0, 0, time.UTC)
+	for _, in := range []string{"23/12/2024", " 2024-12-23 ", "٢٣/١٢/٢٠٢٤"} {
+		got, err := ParseDate(in)
+		if err != nil {
+			t.Errorf("ParseDate(%q) failed: %v", in, err)
+			continue
+		}
+		if !got.Equal(want) {
+			t.Errorf("ParseDate(%q) = %s, want %s", in, got, want)
+		}
+	}
+	for _, in := range []string{"", "12/23/2024", "23-12-2024"} {
+		if _, err := ParseDate(in); err == nil {
+			t.Errorf("ParseDate(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestParseRecord(t *testing.T) {
+	raw := RawRecord{
+		Date:        "23/12/2024",
+		OpenPrice:   "4.230",
+		HighPrice:   "4.250",
+		LowPrice:    "4.200",
+		ClosePrice:  "4.230",
+		Volume:      "236,151,248",
+		TotalShares: "55,827,016",
+		NumTrades:   "-",
+	}
+	record, errs := ParseRecord(0, raw)
+	if len(errs) > 0 {
+		t.Fatalf("ParseRecord failed: %v", errs)
+	}
+	if record.ClosePrice != 4230 || record.Volume != 236151248 || record.NumTrades != 0 || record.Change != 0 {
+		t.Errorf("ParseRecord returned %+v", record)
+	}
+
+	// A truncated row leaves its last cells empty; each is reported
+	raw.TotalShares, raw.NumTrades = "", ""
+	raw.HighPrice = "high"
+	_, errs = ParseRecord(4, raw)
+	if len(errs) != 3 {
+		t.Fatalf("got %d cell errors, want 3: %v", len(errs), errs)
+	}
+	for i, column := range []string{"High", "T.Shares", "Trades"} {
+		if errs[i].Column != column || errs[i].Row != 4 {
+			t.Errorf("error %d is for row %d column %s, want row 4 column %s", i, errs[i].Row, errs[i].Column, column)
+		}
+	}
+	if !errors.Is(errs[1], errEmptyCell) {
+		t.Errorf("empty cell error = %v, want %v", errs[1], errEmptyCell)
+	}
+
+	if _, err := ParseRecords([]RawRecord{raw}); err == nil {
+		t.Error("ParseRecords accepted a row with empty cells")
+	}
+}
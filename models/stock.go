@@ -1,13 +1,61 @@
-// Package models defines the data structures used in the application.
-package models
-
-// StockData represents the structure of stock data.
-type StockData struct {
-	Date      string
-	Close     string
-	Open      string
-	High      string
-	Low       string
-	Volume    string // TShares Volume
-	NumTrades string // No.Trades
-}
+// Package models defines the data structures used in the application.
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PortalDateLayout is the date format used by the ISX portal and legacy CSV files.
+const PortalDateLayout = "02/01/2006"
+
+// DateLayout is the ISO date format used by the current CSV schema.
+const DateLayout = "2006-01-02"
+
+// StockData is one trading day of price history for a ticker.
+type StockData struct {
+	Date        time.Time
+	OpenPrice   Price
+	HighPrice   Price
+	LowPrice    Price
+	ClosePrice  Price
+	Volume      int64 // Traded value
+	TotalShares int64 // Traded shares
+	NumTrades   int64
+	Change      Price
+	ChangePerc  float64
+}
+
+// Price is a fixed-point price in thousandths, the precision the ISX portal
+// quotes prices and changes in.
+type Price int64
+
+// priceScale is the number of Price units per whole unit of currency.
+const priceScale = 1000
+
+// priceDecimals is the number of decimal places a Price can hold.
+const priceDecimals = 3
+
+// Float64 returns the price as a floating point number.
+func (p Price) Float64() float64 {
+	return float64(p) / priceScale
+}
+
+// String formats the price without trailing zeros, e.g. "4.23" or "102".
+func (p Price) String() string {
+	sign := ""
+	v := int64(p)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+
+	whole := v / priceScale
+	frac := v % priceScale
+	if frac == 0 {
+		return fmt.Sprintf("%s%d", sign, whole)
+	}
+	fracStr := strings.TrimRight(fmt.Sprintf("%0*d", priceDecimals, frac), "0")
+	return fmt.Sprintf("%s%d.%s", sign, whole, fracStr)
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Issue is a problem found in a saved price history.
+type Issue struct {
+	Date    time.Time // trading day the problem concerns, zero if none
+	Message string
+}
+
+func (i Issue) String() string {
+	if i.Date.IsZero() {
+		return i.Message
+	}
+	return fmt.Sprintf("%s: %s", i.Date.Format(DateLayout), i.Message)
+}
+
+// Validate checks a price history, which should be newest first, for
+// records the scraper would never write: missing or future dates, repeated
+// or misordered dates, prices outside the day's range and negative counts.
+func Validate(data []StockData) []Issue {
+	var issues []Issue
+	add := func(date time.Time, format string, args ...interface{}) {
+		issues = append(issues, Issue{Date: date, Message: fmt.Sprintf(format, args...)})
+	}
+
+	tomorrow := dateKey(time.Now()).AddDate(0, 0, 1)
+	seen := make(map[time.Time]bool, len(data))
+	for i, record := range data {
+		if record.Date.IsZero() {
+			add(time.Time{}, "row %d has no date", i+1)
+			continue
+		}
+		key := dateKey(record.Date)
+		if !key.Before(tomorrow) {
+			add(record.Date, "date is in the future")
+		}
+		if seen[key] {
+			add(record.Date, "date appears more than once")
+		} else if i > 0 && !data[i-1].Date.IsZero() && key.After(dateKey(data[i-1].Date)) {
+			add(record.Date, "out of order after %s", data[i-1].Date.Format(DateLayout))
+		}
+		seen[key] = true
+
+		if record.ClosePrice <= 0 {
+			add(record.Date, "close price %s is not positive", record.ClosePrice)
+		}
+		if record.HighPrice < record.LowPrice {
+			add(record.Date, "high %s is below low %s", record.HighPrice, record.LowPrice)
+		} else if record.LowPrice > 0 {
+			for _, p := range []struct {
+				name  string
+				value Price
+			}{{"open", record.OpenPrice}, {"close", record.ClosePrice}} {
+				if p.value > 0 && (p.value < record.LowPrice || p.value > record.HighPrice) {
+					add(record.Date, "%s %s is outside the day's range %s-%s", p.name, p.value, record.LowPrice, record.HighPrice)
+				}
+			}
+		}
+		if record.Volume < 0 || record.TotalShares < 0 || record.NumTrades < 0 {
+			add(record.Date, "negative volume, shares or trades")
+		}
+	}
+	return issues
+}
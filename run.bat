@@ -1,4 +1,4 @@
-@echo off
-set GOTMPDIR=C:\GoProjects\webscraper\temp_builds
-go run main.go -ticker BBOB
+@echo off
+set GOTMPDIR=C:\GoProjects\webscraper\temp_builds
+go run ./cmd scrape BBOB
 pause 
\ No newline at end of file